@@ -0,0 +1,282 @@
+package client
+
+import (
+	"context"
+
+	vocab "github.com/go-ap/activitypub"
+	"github.com/go-ap/errors"
+)
+
+// Follow sends a Follow activity for the target actor to the outbox of the actor.
+func (c C) Follow(ctx context.Context, actor, target vocab.Item) (vocab.IRI, vocab.Item, error) {
+	if err := validateActor(actor); err != nil {
+		return "", nil, errors.Annotatef(err, "invalid actor")
+	}
+	if err := validateActor(target); err != nil {
+		return "", nil, errors.Annotatef(err, "invalid Follow target")
+	}
+	return c.ToOutbox(ctx, followActivity(actor, target))
+}
+
+// Unfollow sends an Undo activity for the actor's Follow of the target actor to the outbox of the actor.
+func (c C) Unfollow(ctx context.Context, actor, target vocab.Item) (vocab.IRI, vocab.Item, error) {
+	if err := validateActor(actor); err != nil {
+		return "", nil, errors.Annotatef(err, "invalid actor")
+	}
+	if err := validateActor(target); err != nil {
+		return "", nil, errors.Annotatef(err, "invalid Follow target")
+	}
+	// NOTE(marius): we don't know the IRI of the original Follow, so we embed an equivalent one,
+	//  which servers match by its actor and object.
+	return c.Undo(ctx, actor, followActivity(actor.GetLink(), target))
+}
+
+// Block sends a Block activity for the target actor to the outbox of the actor.
+// The activity isn't addressed to anyone, as servers shouldn't deliver it to the blocked actor.
+//
+// https://www.w3.org/TR/activitypub/#block-activity-outbox
+func (c C) Block(ctx context.Context, actor, target vocab.Item) (vocab.IRI, vocab.Item, error) {
+	if err := validateActor(actor); err != nil {
+		return "", nil, errors.Annotatef(err, "invalid actor")
+	}
+	if err := validateActor(target); err != nil {
+		return "", nil, errors.Annotatef(err, "invalid Block target")
+	}
+	block := &vocab.Activity{
+		Type:   vocab.BlockType,
+		Actor:  actor,
+		Object: target.GetLink(),
+	}
+	return c.ToOutbox(ctx, block)
+}
+
+// Undo sends an Undo activity for the activity to the outbox of the actor, addressed to the recipients of the activity.
+// The activity is embedded in the Undo if it doesn't have an IRI.
+func (c C) Undo(ctx context.Context, actor, activity vocab.Item) (vocab.IRI, vocab.Item, error) {
+	if err := validateActor(actor); err != nil {
+		return "", nil, errors.Annotatef(err, "invalid actor")
+	}
+	act, err := c.loadActivity(ctx, activity)
+	if err != nil {
+		return "", nil, err
+	}
+	undo := &vocab.Activity{
+		Type:   vocab.UndoType,
+		Actor:  actor,
+		Object: act,
+	}
+	if id := act.GetLink(); len(id) > 0 {
+		undo.Object = id
+	}
+	_ = vocab.OnObject(act, func(o *vocab.Object) error {
+		undo.To, undo.CC, undo.Bto, undo.BCC, undo.Audience = o.To, o.CC, o.Bto, o.BCC, o.Audience
+		return nil
+	})
+	return c.ToOutbox(ctx, undo)
+}
+
+// Like sends a Like activity for the object to the outbox of the actor, addressed to the authors of the object.
+func (c C) Like(ctx context.Context, actor, object vocab.Item) (vocab.IRI, vocab.Item, error) {
+	if err := validateActor(actor); err != nil {
+		return "", nil, errors.Annotatef(err, "invalid actor")
+	}
+	ob, err := c.loadObject(ctx, object)
+	if err != nil {
+		return "", nil, err
+	}
+	like := &vocab.Activity{
+		Type:   vocab.LikeType,
+		Actor:  actor,
+		To:     authors(ob),
+		Object: ob.GetLink(),
+	}
+	return c.ToOutbox(ctx, like)
+}
+
+// Boost sends an Announce activity for the object to the outbox of the actor. It is addressed to the public
+// collection, and copied to the followers of the actor and to the authors of the object.
+func (c C) Boost(ctx context.Context, actor, object vocab.Item) (vocab.IRI, vocab.Item, error) {
+	a, err := c.loadActor(ctx, actor)
+	if err != nil {
+		return "", nil, err
+	}
+	ob, err := c.loadObject(ctx, object)
+	if err != nil {
+		return "", nil, err
+	}
+	announce := &vocab.Activity{
+		Type:   vocab.AnnounceType,
+		Actor:  actor,
+		To:     vocab.ItemCollection{vocab.PublicNS},
+		CC:     append(vocab.ItemCollection{followers(a)}, authors(ob)...),
+		Object: ob.GetLink(),
+	}
+	return c.ToOutbox(ctx, announce)
+}
+
+// Reply sends a Create activity for the reply object, as an answer to the inReplyTo object, to the outbox of the actor.
+// If the reply doesn't have any recipients, it is addressed to the public collection, and copied to the followers
+// of the actor and to the authors of the inReplyTo object. The Create activity has the same recipients as the reply.
+func (c C) Reply(ctx context.Context, actor, inReplyTo, reply vocab.Item) (vocab.IRI, vocab.Item, error) {
+	a, err := c.loadActor(ctx, actor)
+	if err != nil {
+		return "", nil, err
+	}
+	parent, err := c.loadObject(ctx, inReplyTo)
+	if err != nil {
+		return "", nil, err
+	}
+	if vocab.IsNil(reply) || vocab.IsIRI(reply) {
+		return "", nil, errf("invalid reply object")
+	}
+	ob, err := vocab.ToObject(reply)
+	if err != nil {
+		return "", nil, errors.Annotatef(err, "invalid reply object")
+	}
+
+	r := *ob
+	r.InReplyTo = parent.GetLink()
+	r.AttributedTo = actor.GetLink()
+	if len(r.To)+len(r.CC)+len(r.Bto)+len(r.BCC)+len(r.Audience) == 0 {
+		r.To = vocab.ItemCollection{vocab.PublicNS}
+		r.CC = append(vocab.ItemCollection{followers(a)}, authors(parent)...)
+	}
+	create := &vocab.Activity{
+		Type:     vocab.CreateType,
+		Actor:    actor,
+		To:       r.To,
+		CC:       r.CC,
+		Bto:      r.Bto,
+		BCC:      r.BCC,
+		Audience: r.Audience,
+		Object:   &r,
+	}
+	return c.ToOutbox(ctx, create)
+}
+
+// DeleteObject sends a Delete activity for the object to the outbox of the actor. The activity has the same
+// recipients as the object, or if it doesn't have any, it is addressed to the public collection, and copied to
+// the followers of the actor.
+func (c C) DeleteObject(ctx context.Context, actor, object vocab.Item) (vocab.IRI, vocab.Item, error) {
+	a, err := c.loadActor(ctx, actor)
+	if err != nil {
+		return "", nil, err
+	}
+	ob, err := c.loadObject(ctx, object)
+	if err != nil {
+		return "", nil, err
+	}
+	del := &vocab.Activity{
+		Type:   vocab.DeleteType,
+		Actor:  actor,
+		Object: ob.GetLink(),
+	}
+	_ = vocab.OnObject(ob, func(o *vocab.Object) error {
+		del.To, del.CC, del.Audience = o.To, o.CC, o.Audience
+		return nil
+	})
+	if len(del.To)+len(del.CC)+len(del.Audience) == 0 {
+		del.To = vocab.ItemCollection{vocab.PublicNS}
+		del.CC = vocab.ItemCollection{followers(a)}
+	}
+	return c.ToOutbox(ctx, del)
+}
+
+// Update sends an Update activity for the object, which contains its new properties, to the outbox of the actor.
+// The activity has the same recipients as the object, or if it doesn't have any, it is addressed to the public
+// collection, and copied to the followers of the actor.
+func (c C) Update(ctx context.Context, actor, object vocab.Item) (vocab.IRI, vocab.Item, error) {
+	if vocab.IsNil(object) || vocab.IsIRI(object) {
+		return "", nil, errf("invalid object to update, it must contain the new properties")
+	}
+	a, err := c.loadActor(ctx, actor)
+	if err != nil {
+		return "", nil, err
+	}
+	update := &vocab.Activity{
+		Type:   vocab.UpdateType,
+		Actor:  actor,
+		Object: object,
+	}
+	_ = vocab.OnObject(object, func(o *vocab.Object) error {
+		update.To, update.CC, update.Bto, update.BCC, update.Audience = o.To, o.CC, o.Bto, o.BCC, o.Audience
+		return nil
+	})
+	if len(update.To)+len(update.CC)+len(update.Bto)+len(update.BCC)+len(update.Audience) == 0 {
+		update.To = vocab.ItemCollection{vocab.PublicNS}
+		update.CC = vocab.ItemCollection{followers(a)}
+	}
+	return c.ToOutbox(ctx, update)
+}
+
+func followActivity(actor, target vocab.Item) *vocab.Activity {
+	return &vocab.Activity{
+		Type:   vocab.FollowType,
+		Actor:  actor,
+		To:     vocab.ItemCollection{target.GetLink()},
+		Object: target.GetLink(),
+	}
+}
+
+// loadObject returns the it object, after loading it if it's only an IRI, as we need its properties for addressing.
+func (c C) loadObject(ctx context.Context, it vocab.Item) (vocab.Item, error) {
+	if err := validateObject(it); err != nil {
+		return nil, errors.Annotatef(err, "invalid object")
+	}
+	if !vocab.IsIRI(it) {
+		return it, nil
+	}
+	ob, err := c.CtxLoadIRI(ctx, it.GetLink())
+	if err != nil {
+		return nil, errors.Annotatef(err, "unable to load object")
+	}
+	return ob, nil
+}
+
+// loadActor returns the it actor, after loading it if it's only an IRI, as we need its collections for addressing.
+func (c C) loadActor(ctx context.Context, it vocab.Item) (vocab.Item, error) {
+	if err := validateActor(it); err != nil {
+		return nil, errors.Annotatef(err, "invalid actor")
+	}
+	if !vocab.IsIRI(it) {
+		return it, nil
+	}
+	a, err := c.CtxLoadIRI(ctx, it.GetLink())
+	if err != nil {
+		return nil, errors.Annotatef(err, "unable to load actor")
+	}
+	if err = validateActor(a); err != nil {
+		return nil, errors.Annotatef(err, "invalid actor")
+	}
+	return a, nil
+}
+
+// loadActivity returns the it activity, after loading it if it's only an IRI.
+func (c C) loadActivity(ctx context.Context, it vocab.Item) (vocab.Item, error) {
+	if vocab.IsNil(it) {
+		return nil, errf("invalid nil activity")
+	}
+	if vocab.IsIRI(it) {
+		act, err := c.CtxLoadIRI(ctx, it.GetLink())
+		if err != nil {
+			return nil, errors.Annotatef(err, "unable to load activity")
+		}
+		it = act
+	}
+	if !vocab.ActivityTypes.Match(it.GetType()) && !vocab.IntransitiveActivityTypes.Match(it.GetType()) {
+		return nil, errf("invalid activity type %v", it.GetType()).iri(it.GetLink())
+	}
+	return it, nil
+}
+
+// authors returns the IRIs of the actors to which the it object is attributed.
+func authors(it vocab.Item) vocab.ItemCollection {
+	var col vocab.ItemCollection
+	_ = vocab.OnObject(it, func(o *vocab.Object) error {
+		for _, iri := range itemIRIs(o.AttributedTo) {
+			_ = col.Append(iri)
+		}
+		return nil
+	})
+	return col
+}
@@ -0,0 +1,250 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"git.sr.ht/~mariusor/lw"
+	vocab "github.com/go-ap/activitypub"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestC_activities(t *testing.T) {
+	docs := map[string]string{
+		"/~jdoe":        `{"id":"http://example.com/~jdoe","type":"Person","outbox":"http://example.com/~jdoe/outbox","followers":"http://example.com/followers/jdoe"}`,
+		"/activities/1": `{"id":"http://example.com/activities/1","type":"Like","actor":"http://example.com/~jdoe","to":["http://example.com/~alice"],"object":"http://example.com/notes/1"}`,
+		"/notes/1":      `{"id":"http://example.com/notes/1","type":"Note","attributedTo":"http://example.com/~alice"}`,
+		"/notes/2":      `{"id":"http://example.com/notes/2","type":"Note","attributedTo":"http://example.com/~jdoe","to":["http://example.com/~alice"]}`,
+	}
+
+	const public = string(vocab.PublicNS)
+	jdoe := vocab.IRI("http://example.com/~jdoe")
+	alice := vocab.IRI("http://example.com/~alice")
+	followers := "http://example.com/followers/jdoe"
+
+	type sent struct {
+		Type   string   `json:"type"`
+		Actor  string   `json:"actor"`
+		To     []string `json:"to"`
+		CC     []string `json:"cc"`
+		Object any      `json:"object"`
+	}
+	tests := []struct {
+		name    string
+		fn      func(context.Context, C) (vocab.IRI, vocab.Item, error)
+		want    *sent
+		wantErr bool
+	}{
+		{
+			name: "Follow",
+			fn: func(ctx context.Context, c C) (vocab.IRI, vocab.Item, error) {
+				return c.Follow(ctx, jdoe, alice)
+			},
+			want: &sent{Type: "Follow", Actor: jdoe.String(), To: []string{alice.String()}, Object: alice.String()},
+		},
+		{
+			name: "Follow with invalid target",
+			fn: func(ctx context.Context, c C) (vocab.IRI, vocab.Item, error) {
+				return c.Follow(ctx, jdoe, &vocab.Object{ID: "http://example.com/notes/1", Type: vocab.NoteType})
+			},
+			wantErr: true,
+		},
+		{
+			name: "Unfollow",
+			fn: func(ctx context.Context, c C) (vocab.IRI, vocab.Item, error) {
+				return c.Unfollow(ctx, jdoe, alice)
+			},
+			want: &sent{
+				Type:  "Undo",
+				Actor: jdoe.String(),
+				To:    []string{alice.String()},
+				Object: map[string]any{
+					"type":   "Follow",
+					"actor":  jdoe.String(),
+					"to":     []any{alice.String()},
+					"object": alice.String(),
+				},
+			},
+		},
+		{
+			name: "Block",
+			fn: func(ctx context.Context, c C) (vocab.IRI, vocab.Item, error) {
+				return c.Block(ctx, jdoe, alice)
+			},
+			want: &sent{Type: "Block", Actor: jdoe.String(), Object: alice.String()},
+		},
+		{
+			name: "Undo",
+			fn: func(ctx context.Context, c C) (vocab.IRI, vocab.Item, error) {
+				return c.Undo(ctx, jdoe, vocab.IRI("http://example.com/activities/1"))
+			},
+			want: &sent{Type: "Undo", Actor: jdoe.String(), To: []string{alice.String()}, Object: "http://example.com/activities/1"},
+		},
+		{
+			name: "Undo an object which is not an activity",
+			fn: func(ctx context.Context, c C) (vocab.IRI, vocab.Item, error) {
+				return c.Undo(ctx, jdoe, vocab.IRI("http://example.com/notes/1"))
+			},
+			wantErr: true,
+		},
+		{
+			name: "Like",
+			fn: func(ctx context.Context, c C) (vocab.IRI, vocab.Item, error) {
+				return c.Like(ctx, jdoe, vocab.IRI("http://example.com/notes/1"))
+			},
+			want: &sent{Type: "Like", Actor: jdoe.String(), To: []string{alice.String()}, Object: "http://example.com/notes/1"},
+		},
+		{
+			name: "Like an object that can't be loaded",
+			fn: func(ctx context.Context, c C) (vocab.IRI, vocab.Item, error) {
+				return c.Like(ctx, jdoe, vocab.IRI("http://example.com/notes/404"))
+			},
+			wantErr: true,
+		},
+		{
+			name: "Boost",
+			fn: func(ctx context.Context, c C) (vocab.IRI, vocab.Item, error) {
+				return c.Boost(ctx, jdoe, vocab.IRI("http://example.com/notes/1"))
+			},
+			want: &sent{
+				Type:   "Announce",
+				Actor:  jdoe.String(),
+				To:     []string{public},
+				CC:     []string{followers, alice.String()},
+				Object: "http://example.com/notes/1",
+			},
+		},
+		{
+			name: "Boost by an actor that can't be loaded",
+			fn: func(ctx context.Context, c C) (vocab.IRI, vocab.Item, error) {
+				return c.Boost(ctx, vocab.IRI("http://example.com/~missing"), vocab.IRI("http://example.com/notes/1"))
+			},
+			wantErr: true,
+		},
+		{
+			name: "Reply",
+			fn: func(ctx context.Context, c C) (vocab.IRI, vocab.Item, error) {
+				return c.Reply(ctx, jdoe, vocab.IRI("http://example.com/notes/1"), &vocab.Object{Type: vocab.NoteType})
+			},
+			want: &sent{
+				Type:  "Create",
+				Actor: jdoe.String(),
+				To:    []string{public},
+				CC:    []string{followers, alice.String()},
+				Object: map[string]any{
+					"type":         "Note",
+					"attributedTo": jdoe.String(),
+					"inReplyTo":    "http://example.com/notes/1",
+					"to":           []any{public},
+					"cc":           []any{followers, alice.String()},
+				},
+			},
+		},
+		{
+			name: "Reply with recipients",
+			fn: func(ctx context.Context, c C) (vocab.IRI, vocab.Item, error) {
+				reply := &vocab.Object{Type: vocab.NoteType, To: vocab.ItemCollection{alice}}
+				return c.Reply(ctx, jdoe, vocab.IRI("http://example.com/notes/1"), reply)
+			},
+			want: &sent{
+				Type:  "Create",
+				Actor: jdoe.String(),
+				To:    []string{alice.String()},
+				Object: map[string]any{
+					"type":         "Note",
+					"attributedTo": jdoe.String(),
+					"inReplyTo":    "http://example.com/notes/1",
+					"to":           []any{alice.String()},
+				},
+			},
+		},
+		{
+			name: "Reply with invalid object",
+			fn: func(ctx context.Context, c C) (vocab.IRI, vocab.Item, error) {
+				return c.Reply(ctx, jdoe, vocab.IRI("http://example.com/notes/1"), vocab.IRI("http://example.com/notes/2"))
+			},
+			wantErr: true,
+		},
+		{
+			name: "DeleteObject",
+			fn: func(ctx context.Context, c C) (vocab.IRI, vocab.Item, error) {
+				return c.DeleteObject(ctx, jdoe, &vocab.Object{ID: "http://example.com/notes/3", Type: vocab.NoteType})
+			},
+			want: &sent{
+				Type:   "Delete",
+				Actor:  jdoe.String(),
+				To:     []string{public},
+				CC:     []string{followers},
+				Object: "http://example.com/notes/3",
+			},
+		},
+		{
+			name: "Update",
+			fn: func(ctx context.Context, c C) (vocab.IRI, vocab.Item, error) {
+				return c.Update(ctx, jdoe, &vocab.Object{ID: "http://example.com/notes/3", Type: vocab.NoteType})
+			},
+			want: &sent{
+				Type:   "Update",
+				Actor:  jdoe.String(),
+				To:     []string{public},
+				CC:     []string{followers},
+				Object: map[string]any{"id": "http://example.com/notes/3", "type": "Note"},
+			},
+		},
+		{
+			name: "Update with only an IRI",
+			fn: func(ctx context.Context, c C) (vocab.IRI, vocab.Item, error) {
+				return c.Update(ctx, jdoe, vocab.IRI("http://example.com/notes/2"))
+			},
+			wantErr: true,
+		},
+		{
+			name: "DeleteObject with the object's recipients",
+			fn: func(ctx context.Context, c C) (vocab.IRI, vocab.Item, error) {
+				return c.DeleteObject(ctx, jdoe, vocab.IRI("http://example.com/notes/2"))
+			},
+			want: &sent{Type: "Delete", Actor: jdoe.String(), To: []string{alice.String()}, Object: "http://example.com/notes/2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got *sent
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodPost {
+					if r.URL.Path != "/~jdoe/outbox" {
+						w.WriteHeader(http.StatusNotFound)
+						return
+					}
+					body, _ := io.ReadAll(r.Body)
+					got = new(sent)
+					if err := json.Unmarshal(body, got); err != nil {
+						t.Errorf("invalid activity posted: %s", err)
+					}
+					w.WriteHeader(http.StatusCreated)
+					return
+				}
+				doc, ok := docs[r.URL.Path]
+				if !ok {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				w.Header().Set("Content-Type", ContentTypeJsonActivity)
+				_, _ = w.Write([]byte(doc))
+			}))
+			defer srv.Close()
+
+			c := C{c: srv.Client(), l: lw.Dev(lw.SetOutput(t.Output()))}
+			_, _, err := tt.fn(context.Background(), c)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if !cmp.Equal(got, tt.want) {
+				t.Errorf("posted activity = %s", cmp.Diff(tt.want, got))
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package client
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultAuthTTL is the duration for which the client keeps using the authorization function that last succeeded
+// for a host, when not configured otherwise.
+const defaultAuthTTL = time.Hour
+
+// AuthRecord is the authorization function that last succeeded for a host.
+type AuthRecord struct {
+	// Index is the position of the authorization function in the order they've been passed to the client,
+	// or -1 if the request succeeded without authorization.
+	Index int `json:"index"`
+	// Updated is the time of the last successful request.
+	Updated time.Time `json:"updated"`
+}
+
+// AuthStore is the storage for the AuthRecord values of the hosts that the client made requests to.
+type AuthStore interface {
+	// Load returns the AuthRecord saved for the host, or an empty one if nothing has been saved yet.
+	Load(host string) (AuthRecord, error)
+	// Save stores the rec AuthRecord for the host.
+	Save(host string, rec AuthRecord) error
+}
+
+// WithAuthMemory makes the client remember, in the store [AuthStore], the authorization function that last
+// succeeded for every host, and try it first for the next requests to the same host, for the ttl duration.
+// A nil store disables this.
+func WithAuthMemory(store AuthStore, ttl time.Duration) OptionFn {
+	return func(c *C) {
+		c.authStore = store
+		c.authTTL = ttl
+	}
+}
+
+type memAuthStore struct {
+	mu sync.RWMutex
+	m  map[string]AuthRecord
+}
+
+// MemAuthStore returns an AuthStore which keeps the records in memory.
+func MemAuthStore() AuthStore {
+	return &memAuthStore{m: make(map[string]AuthRecord)}
+}
+
+func (s *memAuthStore) Load(host string) (AuthRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.m[host], nil
+}
+
+func (s *memAuthStore) Save(host string, rec AuthRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.m[host] = rec
+	return nil
+}
+
+func (c C) authMemoryTTL() time.Duration {
+	if c.authTTL <= 0 {
+		return defaultAuthTTL
+	}
+	return c.authTTL
+}
+
+// rememberedFirst moves the authorization function that last succeeded for the host to the front of the order.
+// It also returns the record of that function.
+func (c C) rememberedFirst(host string, order []int) ([]int, AuthRecord) {
+	if c.authStore == nil {
+		return order, AuthRecord{}
+	}
+	rec, err := c.authStore.Load(strings.ToLower(host))
+	if err != nil {
+		c.l.WithContext(Ctx{"host": host, "err": err.Error()}).Warnf("unable to load authorization record")
+		return order, AuthRecord{}
+	}
+	if rec.Updated.IsZero() || TimeNow().Sub(rec.Updated) > c.authMemoryTTL() {
+		return order, AuthRecord{}
+	}
+	i := slices.Index(order, rec.Index)
+	if i < 0 {
+		return order, AuthRecord{}
+	}
+	return slices.Concat([]int{rec.Index}, order[:i], order[i+1:]), rec
+}
+
+// remember saves the authorization function that succeeded for the host, unless the prev record
+// already contains it, and it is recent enough.
+func (c C) remember(host string, idx int, prev AuthRecord) {
+	if c.authStore == nil {
+		return
+	}
+	now := TimeNow()
+	if !prev.Updated.IsZero() && prev.Index == idx && now.Sub(prev.Updated) < c.authMemoryTTL()/2 {
+		return
+	}
+	rec := AuthRecord{Index: idx, Updated: now}
+	if err := c.authStore.Save(strings.ToLower(host), rec); err != nil {
+		c.l.WithContext(Ctx{"host": host, "err": err.Error()}).Warnf("unable to save authorization record")
+	}
+}
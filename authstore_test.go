@@ -0,0 +1,98 @@
+package client
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"git.sr.ht/~mariusor/lw"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestC_Do_withAuthMemory(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	TimeNow = func() time.Time { return now }
+	defer func() {
+		TimeNow = mockTimeFn
+	}()
+
+	tests := []struct {
+		name     string
+		prev     *AuthRecord
+		accepted string
+		wantAuth [][]string
+		wantRec  AuthRecord
+	}{
+		{
+			name:     "first function succeeds",
+			accepted: "first",
+			wantAuth: [][]string{{"first"}, {"first"}},
+			wantRec:  AuthRecord{Index: 0, Updated: now},
+		},
+		{
+			name:     "second function is remembered",
+			accepted: "second",
+			wantAuth: [][]string{{"first", "second"}, {"second"}},
+			wantRec:  AuthRecord{Index: 1, Updated: now},
+		},
+		{
+			name:     "expired record is ignored",
+			prev:     &AuthRecord{Index: 1, Updated: now.Add(-2 * time.Hour)},
+			accepted: "first",
+			wantAuth: [][]string{{"first"}, {"first"}},
+			wantRec:  AuthRecord{Index: 0, Updated: now},
+		},
+		{
+			name:     "falls back when the remembered function fails",
+			prev:     &AuthRecord{Index: 1, Updated: now.Add(-time.Minute)},
+			accepted: "first",
+			wantAuth: [][]string{{"second", "first"}, {"first"}},
+			wantRec:  AuthRecord{Index: 0, Updated: now},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotAuth []string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				auth := r.Header.Get("Authorization")
+				gotAuth = append(gotAuth, auth)
+				if auth != tt.accepted {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer srv.Close()
+
+			store := MemAuthStore()
+			if tt.prev != nil {
+				_ = store.Save("example.com", *tt.prev)
+			}
+			c := C{c: srv.Client(), l: lw.Dev(lw.SetOutput(t.Output()))}
+			for _, fn := range []OptionFn{
+				WithAuthorizationFn(mockAuthFn("first"), mockAuthFn("second")),
+				WithAuthMemory(store, time.Hour),
+			} {
+				fn(&c)
+			}
+
+			for i, want := range tt.wantAuth {
+				gotAuth = nil
+				res, err := c.Do(mockGetReq())
+				if err != nil {
+					t.Fatalf("Do() error = %s", err)
+				}
+				if res.StatusCode != http.StatusOK {
+					t.Errorf("Do() status = %d, want %d", res.StatusCode, http.StatusOK)
+				}
+				if !cmp.Equal(gotAuth, want) {
+					t.Errorf("Do() request %d authorizations = %s", i, cmp.Diff(want, gotAuth))
+				}
+			}
+			if got, _ := store.Load("example.com"); !cmp.Equal(got, tt.wantRec) {
+				t.Errorf("Load() = %s", cmp.Diff(tt.wantRec, got))
+			}
+		})
+	}
+}
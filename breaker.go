@@ -0,0 +1,175 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-ap/errors"
+)
+
+// CircuitState is the state of the circuit breaker of a host.
+type CircuitState int8
+
+const (
+	// CircuitClosed means that the requests to the host are sent normally.
+	CircuitClosed CircuitState = iota
+	// CircuitOpen means that the requests to the host fail without being sent.
+	CircuitOpen
+	// CircuitHalfOpen means that a single request is sent to the host, to check if it recovered.
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// CircuitOpenError is returned for the requests to a Host, while its circuit is open.
+type CircuitOpenError struct {
+	Host string
+	// Until is the time when the client will try to send a request to the Host again.
+	Until time.Time
+}
+
+func (e CircuitOpenError) Error() string {
+	return fmt.Sprintf("circuit open for %s until %s", e.Host, e.Until.Format(time.RFC3339))
+}
+
+// WithCircuitBreaker makes the client stop sending requests to the hosts that failed with connection errors,
+// or with 5xx response statuses, for the failures consecutive times. For the cooldown duration, the requests
+// to these hosts fail immediately with a [CircuitOpenError], after which a single request is allowed to check
+// if the host recovered.
+// The stateFns get called on every change of state of the circuit of a host.
+func WithCircuitBreaker(failures int, cooldown time.Duration, stateFns ...func(host string, from, to CircuitState)) OptionFn {
+	return func(c *C) {
+		c.breaker = newBreaker(failures, cooldown, stateFns...)
+	}
+}
+
+type circuit struct {
+	state    CircuitState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// breaker keeps a circuit for every host.
+// A nil breaker lets all requests through.
+type breaker struct {
+	mu       sync.Mutex
+	max      int
+	cooldown time.Duration
+	stateFns []func(string, CircuitState, CircuitState)
+	circuits map[string]*circuit
+}
+
+func newBreaker(failures int, cooldown time.Duration, stateFns ...func(string, CircuitState, CircuitState)) *breaker {
+	if failures <= 0 {
+		return nil
+	}
+	return &breaker{
+		max:      failures,
+		cooldown: cooldown,
+		stateFns: stateFns,
+		circuits: make(map[string]*circuit),
+	}
+}
+
+func (b *breaker) circuit(host string) *circuit {
+	host = strings.ToLower(host)
+	cc, ok := b.circuits[host]
+	if !ok {
+		cc = &circuit{}
+		b.circuits[host] = cc
+	}
+	return cc
+}
+
+func (b *breaker) notify(host string, from, to CircuitState) {
+	if from == to {
+		return
+	}
+	for _, fn := range b.stateFns {
+		fn(host, from, to)
+	}
+}
+
+// allow returns an error if requests to the host can not be sent.
+func (b *breaker) allow(host string) error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	cc := b.circuit(host)
+	from := cc.state
+	until := cc.openedAt.Add(b.cooldown)
+	switch cc.state {
+	case CircuitOpen:
+		if time.Now().Before(until) {
+			b.mu.Unlock()
+			return CircuitOpenError{Host: host, Until: until}
+		}
+		cc.state = CircuitHalfOpen
+		cc.probing = true
+	case CircuitHalfOpen:
+		if cc.probing {
+			b.mu.Unlock()
+			return CircuitOpenError{Host: host, Until: until}
+		}
+		cc.probing = true
+	}
+	to := cc.state
+	b.mu.Unlock()
+
+	b.notify(host, from, to)
+	return nil
+}
+
+// record updates the circuit of the host with the result of a request.
+func (b *breaker) record(host string, res *http.Response, err error) {
+	if b == nil {
+		return
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		// NOTE(marius): the request has been abandoned by the caller, which says nothing about the host
+		b.mu.Lock()
+		b.circuit(host).probing = false
+		b.mu.Unlock()
+		return
+	}
+	failed := err != nil || (res != nil && res.StatusCode >= http.StatusInternalServerError)
+
+	b.mu.Lock()
+	cc := b.circuit(host)
+	from := cc.state
+	cc.probing = false
+	switch {
+	case !failed:
+		cc.state = CircuitClosed
+		cc.failures = 0
+	case cc.state == CircuitHalfOpen:
+		cc.state = CircuitOpen
+		cc.openedAt = time.Now()
+	default:
+		cc.failures++
+		if cc.failures >= b.max {
+			cc.state = CircuitOpen
+			cc.openedAt = time.Now()
+		}
+	}
+	to := cc.state
+	b.mu.Unlock()
+
+	b.notify(host, from, to)
+}
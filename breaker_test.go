@@ -0,0 +1,116 @@
+package client
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"git.sr.ht/~mariusor/lw"
+	"github.com/go-ap/errors"
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_breaker(t *testing.T) {
+	type change struct {
+		host     string
+		from, to CircuitState
+	}
+	var changes []change
+	b := newBreaker(2, 20*time.Millisecond, func(host string, from, to CircuitState) {
+		changes = append(changes, change{host, from, to})
+	})
+
+	failed := &http.Response{StatusCode: http.StatusBadGateway}
+	ok := &http.Response{StatusCode: http.StatusNotFound}
+
+	b.record("example.com", failed, nil)
+	b.record("example.com", ok, nil)
+	b.record("example.com", failed, nil)
+	if err := b.allow("example.com"); err != nil {
+		t.Fatalf("allow() error = %s, the failures were not consecutive", err)
+	}
+	b.record("example.com", nil, errors.Newf("connection refused"))
+
+	err := b.allow("EXAMPLE.com")
+	coe := CircuitOpenError{}
+	if !errors.As(err, &coe) {
+		t.Fatalf("allow() error = %v, expected a CircuitOpenError", err)
+	}
+	if err = b.allow("example.org"); err != nil {
+		t.Errorf("allow() error = %s for a different host", err)
+	}
+
+	time.Sleep(25 * time.Millisecond)
+	if err = b.allow("example.com"); err != nil {
+		t.Fatalf("allow() error = %s after the cooldown", err)
+	}
+	if err = b.allow("example.com"); !errors.As(err, &coe) {
+		t.Fatalf("allow() error = %v, expected a CircuitOpenError while the probe is in flight", err)
+	}
+	b.record("example.com", failed, nil)
+	if err = b.allow("example.com"); !errors.As(err, &coe) {
+		t.Fatalf("allow() error = %v, expected a CircuitOpenError after the probe failed", err)
+	}
+
+	time.Sleep(25 * time.Millisecond)
+	if err = b.allow("example.com"); err != nil {
+		t.Fatalf("allow() error = %s after the cooldown", err)
+	}
+	b.record("example.com", ok, nil)
+	if err = b.allow("example.com"); err != nil {
+		t.Fatalf("allow() error = %s after the probe succeeded", err)
+	}
+
+	wantChanges := []change{
+		{"example.com", CircuitClosed, CircuitOpen},
+		{"example.com", CircuitOpen, CircuitHalfOpen},
+		{"example.com", CircuitHalfOpen, CircuitOpen},
+		{"example.com", CircuitOpen, CircuitHalfOpen},
+		{"example.com", CircuitHalfOpen, CircuitClosed},
+	}
+	if !cmp.Equal(changes, wantChanges, cmp.AllowUnexported(change{})) {
+		t.Errorf("state changes = %s", cmp.Diff(wantChanges, changes, cmp.AllowUnexported(change{})))
+	}
+
+	var nilBreaker *breaker
+	nilBreaker.record("example.com", failed, nil)
+	if err = nilBreaker.allow("example.com"); err != nil {
+		t.Errorf("allow() on nil breaker error = %s", err)
+	}
+}
+
+func TestC_Do_withCircuitBreaker(t *testing.T) {
+	calls := atomic.Int32{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	c := C{c: srv.Client(), l: lw.Dev(lw.SetOutput(t.Output()))}
+	WithCircuitBreaker(3, time.Hour)(&c)
+
+	for range 3 {
+		res, err := c.Do(mockGetReq())
+		if err != nil {
+			t.Fatalf("Do() error = %s", err)
+		}
+		if res.StatusCode != http.StatusInternalServerError {
+			t.Errorf("Do() status = %d", res.StatusCode)
+		}
+	}
+
+	_, err := c.Do(mockGetReq())
+	coe := CircuitOpenError{}
+	if !errors.As(err, &coe) {
+		t.Fatalf("Do() error = %v, expected a CircuitOpenError", err)
+	}
+	if coe.Host != "example.com" || time.Until(coe.Until) < 59*time.Minute {
+		t.Errorf("Do() error = %#v", coe)
+	}
+	if got := calls.Load(); got != 3 {
+		t.Errorf("Do() calls = %d, the request should not have been sent while the circuit is open", got)
+	}
+}
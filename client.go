@@ -8,6 +8,8 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"slices"
+	"sync"
 	"time"
 
 	"git.sr.ht/~mariusor/cache"
@@ -62,6 +64,41 @@ type C struct {
 	ua       string
 	authFns  []func(*http.Request) error
 	proxyURL vocab.IRI
+	proxied  *proxiedHosts
+
+	// actor is the actor on whose behalf the client operates, and endpoints are the ones it advertises.
+	actor     vocab.Item
+	endpoints *actorEndpoints
+
+	// authSchemes contains the schemes of the authFns, when known.
+	authSchemes []AuthScheme
+	profiles    *Profiles
+	authStore   AuthStore
+	authTTL     time.Duration
+	retry       *RetryPolicy
+	rateLimit   *rateLimiter
+	breaker     *breaker
+
+	prefetch     int
+	prefetchHost *hostLimiter
+
+	localFilters bool
+
+	batchSize int
+	batchHost *hostLimiter
+
+	postWorkers int
+
+	inflight *inflight
+
+	hydrateLimit int
+
+	threadAncestors int
+	threadDepth     int
+	threadSize      int
+	threadContext   bool
+
+	nodeInfo *nodeInfoCache
 }
 
 // WithHTTPClient sets the http client
@@ -127,6 +164,66 @@ func WithProxyURL(u vocab.IRI) OptionFn {
 	}
 }
 
+// WithPrefetch makes the collection iterators load up to pages collection pages ahead of the one
+// that is being consumed. Besides the "next" page, the following ones can be loaded only if their IRIs
+// are predictable, like for the pages that have an incrementing numeric query parameter, and only as many
+// as the totalItems of the collection allows, or, if it doesn't report it, after the server confirmed the prediction.
+// The perHost value limits the number of concurrent page requests to the same host, zero meaning no limit.
+func WithPrefetch(pages, perHost int) OptionFn {
+	return func(c *C) {
+		c.prefetch = pages
+		c.prefetchHost = newHostLimiter(perHost)
+	}
+}
+
+// WithBatchLimits sets the maximum number of concurrent requests that [C.LoadIRIs] does in total,
+// and for the same host. A zero perHost value means that only the total is limited.
+func WithBatchLimits(total, perHost int) OptionFn {
+	return func(c *C) {
+		c.batchSize = total
+		c.batchHost = newHostLimiter(perHost)
+	}
+}
+
+// WithParallelPosting makes [C.ToCollection], [C.CtxToCollection] and [C.ToCollections] post to up to workers target
+// collections concurrently, instead of one after the other. The results are returned in the order of the collections,
+// and [C.CtxToCollection] returns the errors of all the failed ones.
+func WithParallelPosting(workers int) OptionFn {
+	return func(c *C) {
+		c.postWorkers = workers
+	}
+}
+
+// FilteredBy designates where the filters of a collection request have been applied.
+type FilteredBy int8
+
+const (
+	// FilteredByServer means that all the received items were matching the filters,
+	// so the server has most likely applied them.
+	FilteredByServer FilteredBy = iota + 1
+	// FilteredByClient means that some of the received items were not matching the filters,
+	// so they have been removed by the client.
+	FilteredByClient
+)
+
+// WithLocalFiltering makes the client apply the [filters.Check] list passed to the PubGetter methods and to
+// the collection iterators on the received items, for servers which ignore the filtering query parameters.
+// Use [ReportFiltering] to find out where the filtering of a request has been done.
+func WithLocalFiltering() OptionFn {
+	return func(c *C) {
+		c.localFilters = true
+	}
+}
+
+type filterReportKey struct{}
+
+// ReportFiltering returns a copy of ctx which makes the client call fn for every collection document that it
+// filters locally while loading it with ctx, with where the filtering has been done.
+// It has an effect only on clients configured with [WithLocalFiltering].
+func ReportFiltering(ctx context.Context, fn func(vocab.IRI, FilteredBy)) context.Context {
+	return context.WithValue(ctx, filterReportKey{}, fn)
+}
+
 // OptionFn is the type designating setup functions accepted by the [client.New] initializer.
 type OptionFn func(s *C)
 
@@ -153,7 +250,17 @@ var (
 )
 
 func New(o ...OptionFn) *C {
-	c := &C{c: defaultClient, ua: UserAgent, l: nilLogger}
+	c := &C{
+		c:         defaultClient,
+		ua:        UserAgent,
+		l:         nilLogger,
+		inflight:  newInflight(),
+		nodeInfo:  newNodeInfoCache(),
+		profiles:  NewProfiles(),
+		authStore: MemAuthStore(),
+		proxied:   newProxiedHosts(),
+		endpoints: newActorEndpoints(),
+	}
 	for _, fn := range o {
 		fn(c)
 	}
@@ -162,7 +269,15 @@ func New(o ...OptionFn) *C {
 
 var TimeNow = func() time.Time { return time.Now().Truncate(time.Millisecond).UTC() }
 
+// loadCtx dereferences the id [vocab.IRI]. Concurrent loads of the same IRI done through the same client
+// share a single request, and they all receive the same decoded item, which callers should not modify.
 func (c C) loadCtx(ctx context.Context, id vocab.IRI) (vocab.Item, error) {
+	return c.inflight.do(ctx, id, func(ctx context.Context) (vocab.Item, error) {
+		return c.load(ctx, id)
+	})
+}
+
+func (c C) load(ctx context.Context, id vocab.IRI) (vocab.Item, error) {
 	errCtx := Ctx{"IRI": id}
 	st := TimeNow()
 	if len(id) == 0 {
@@ -255,6 +370,74 @@ func (c C) LoadIRI(id vocab.IRI) (vocab.Item, error) {
 	return c.loadCtx(context.Background(), id)
 }
 
+// defaultBatchSize is the number of concurrent requests [C.LoadIRIs] does when not configured otherwise.
+const defaultBatchSize = 8
+
+// LoadResult is the outcome of dereferencing one of the IRIs passed to [C.LoadIRIs].
+type LoadResult struct {
+	Item vocab.Item
+	Err  error
+}
+
+// LoadIRIs dereferences the iris [vocab.IRI] list concurrently, and returns the results keyed by IRI.
+// Duplicate IRIs are loaded only once. The number of concurrent requests can be configured using [WithBatchLimits].
+func (c C) LoadIRIs(ctx context.Context, iris ...vocab.IRI) map[vocab.IRI]LoadResult {
+	result := make(map[vocab.IRI]LoadResult, len(iris))
+	if len(iris) == 0 {
+		return result
+	}
+
+	size := c.batchSize
+	if size <= 0 {
+		size = defaultBatchSize
+	}
+	slots := make(chan struct{}, size)
+
+	unique := make(map[vocab.IRI]struct{}, len(iris))
+	for _, iri := range iris {
+		unique[iri] = struct{}{}
+	}
+
+	mu := sync.Mutex{}
+	wg := sync.WaitGroup{}
+	for iri := range unique {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			res := c.batchLoad(ctx, slots, iri)
+			mu.Lock()
+			result[iri] = res
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	return result
+}
+
+func (c C) batchLoad(ctx context.Context, slots chan struct{}, iri vocab.IRI) LoadResult {
+	if err := ctx.Err(); err != nil {
+		return LoadResult{Err: err}
+	}
+	// NOTE(marius): we wait for the host slot first, so the requests to a busy host don't hold
+	//  the batch slots that the requests to the other hosts could use.
+	host := hostOf(iri)
+	if err := c.batchHost.acquire(ctx, host); err != nil {
+		return LoadResult{Err: err}
+	}
+	defer c.batchHost.release(host)
+
+	select {
+	case slots <- struct{}{}:
+	case <-ctx.Done():
+		return LoadResult{Err: ctx.Err()}
+	}
+	defer func() { <-slots }()
+
+	it, err := c.loadCtx(ctx, iri)
+	return LoadResult{Item: it, Err: err}
+}
+
 func (c C) FetchRequest(ctx context.Context, url string) (*http.Request, error) {
 	return FetchRequest(ctx, url, http.MethodGet)
 }
@@ -269,6 +452,16 @@ var ErrRetry = errors.Newf("retry")
 // The clone is a shallow copy of the struct and its Header map.
 func cloneRequest(r *http.Request, last bool) *http.Request {
 	r2 := r.Clone(r.Context())
+	if _, ok := r.Body.(*lazyBody); ok && r.GetBody != nil {
+		// NOTE(marius): the body is one of the streamed ones that we create, which can be recreated,
+		//  so we don't need to buffer it. We don't rely on other GetBody functions, as the ones set by
+		//  some request builders return the same reader every time.
+		if body, err := r.GetBody(); err == nil {
+			r2.Body = body
+			_ = r.Body.Close()
+			return r2
+		}
+	}
 	if r.Body != nil {
 		ob := r.Body
 		buff, err := io.ReadAll(r.Body)
@@ -295,14 +488,38 @@ func (c C) Do(req *http.Request) (*http.Response, error) {
 		req.Header.Set("User-Agent", c.ua)
 	}
 
+	if c.retry != nil {
+		return c.doWithRetries(req)
+	}
+	return c.do(req)
+}
+
+func (c C) do(req *http.Request) (*http.Response, error) {
 	if len(c.authFns) > 0 {
 		return c.doRetry(req)
 	}
 	// NOTE(marius): try without a signing function
-	return c.c.Do(req)
+	return c.send(req)
+}
+
+// send does the actual round trip of the req, once the limits of its host allow it.
+func (c C) send(req *http.Request) (*http.Response, error) {
+	if err := c.rateLimit.wait(req.Context(), req.URL.Host); err != nil {
+		return nil, err
+	}
+	if err := c.breaker.allow(req.URL.Host); err != nil {
+		return nil, err
+	}
+	res, err := c.c.Do(req)
+	c.breaker.record(req.URL.Host, res, err)
+	c.rateLimit.observe(req.URL.Host, res)
+	return res, err
 }
 
 func (c C) doRetry(req *http.Request) (res *http.Response, err error) {
+	profile := c.profiles.Host(req.URL.Host)
+	order, rec := c.rememberedFirst(req.URL.Host, c.authOrder(req, profile))
+
 	try := 0
 	roundTripFn := func(req *http.Request) (*http.Response, error) {
 		lc := lw.Ctx{}
@@ -311,47 +528,35 @@ func (c C) doRetry(req *http.Request) (res *http.Response, err error) {
 			lc["retry"] = try
 		}
 
-		res, err := c.c.Do(req)
+		res, err := c.send(req)
 		// NOTE(marius): the client failed for some reason, or we tried with all signing functions.
-		if try == len(c.authFns)-1 || err != nil {
+		if try == len(order)-1 || err != nil {
 			return res, err
 		}
 		try++
 
-		switch res.StatusCode {
-		case http.StatusServiceUnavailable:
-			// NOTE(marius): this is a hack for mastoart.social
-			// which returns a 503 if it encountered previous errors.
-			fallthrough
-		case http.StatusBadRequest:
-			// NOTE(marius): this is a hack for tags.pub that doesn't
-			// return a 403 or 401 error status on failing signatures
-			// See https://todo.sr.ht/~mariusor/go-activitypub/473
-			fallthrough
-		case http.StatusNotFound:
-			// NOTE(marius): many services, among which the GoActivityPub ones, return not found
-			// for resources that are actually forbidden.
-			fallthrough
-		case http.StatusUnauthorized, http.StatusForbidden:
-			// NOTE(marius): Not an acceptable response status, so we want to try again.
-			lc["status"] = res.StatusCode
-			c.l.WithContext(lc).Errorf("error response from remote server")
-			_, _ = io.Copy(io.Discard, res.Body)
-			_ = res.Body.Close()
-
-			return nil, ErrRetry
-		default:
+		if !slices.Contains(profile.RetryStatuses, res.StatusCode) {
 			// NOTE(marius): some kind of success
 			return res, nil
 		}
+		// NOTE(marius): Not an acceptable response status, so we want to try again.
+		lc["status"] = res.StatusCode
+		c.l.WithContext(lc).Errorf("error response from remote server")
+		_, _ = io.Copy(io.Discard, res.Body)
+		_ = res.Body.Close()
+
+		return nil, ErrRetry
 	}
 
-	for i, signFn := range c.authFns {
-		r2 := cloneRequest(req, i == len(c.authFns)-1)
-		if err = signFn(r2); err != nil {
+	for i, idx := range order {
+		r2 := cloneRequest(req, i == len(order)-1)
+		if err = c.authFn(idx)(r2); err != nil {
 			continue
 		}
 		res, err = roundTripFn(r2)
+		if err == nil && !slices.Contains(profile.RetryStatuses, res.StatusCode) {
+			c.remember(req.URL.Host, idx, rec)
+		}
 		if err == nil || !errors.Is(err, ErrRetry) {
 			break
 		}
@@ -365,23 +570,42 @@ func (c C) CtxGet(ctx context.Context, url string) (*http.Response, error) {
 	if err != nil {
 		return nil, err
 	}
-	return c.Do(req)
+	return c.tryProxiedRequest(req)
 }
 
 func (c C) toCollections(ctx context.Context, act vocab.Item, colIRI ...vocab.IRI) (vocab.IRI, vocab.Item, error) {
 	result := make(vocab.ItemCollection, 0, len(colIRI))
 	actIRIs := make(vocab.IRIs, 0, len(colIRI))
 
-	for _, iri := range colIRI {
-		actIRI, it, err := c.toCollection(ctx, act, iri)
-		if err != nil {
-			return "", result, err
+	if c.postWorkers > 1 {
+		var errs []error
+		for _, r := range c.ToCollections(ctx, act, colIRI...) {
+			if r.Err != nil {
+				errs = append(errs, r.Err)
+				continue
+			}
+			if !vocab.IsNil(r.Item) {
+				_ = result.Append(r.Item)
+			}
+			if !r.IRI.Equal("") {
+				_ = actIRIs.Append(r.IRI)
+			}
 		}
-		if !vocab.IsNil(it) {
-			_ = result.Append(it)
+		if len(errs) > 0 {
+			return "", result, errors.Join(errs...)
 		}
-		if !actIRI.Equal("") {
-			_ = actIRIs.Append(actIRI)
+	} else {
+		for _, iri := range colIRI {
+			actIRI, it, err := c.toCollection(ctx, act, iri)
+			if err != nil {
+				return "", result, err
+			}
+			if !vocab.IsNil(it) {
+				_ = result.Append(it)
+			}
+			if !actIRI.Equal("") {
+				_ = actIRIs.Append(actIRI)
+			}
 		}
 	}
 
@@ -390,6 +614,7 @@ func (c C) toCollections(ctx context.Context, act vocab.Item, colIRI ...vocab.IR
 
 	// NOTE(marius): currently I don't know how to return multiple IRIs if we have multiple actors,
 	//  so we currently do the wrong thing for len(iris) > 1 and return only the IRI of the first activity.
+	//  The callers that need all of them should use ToCollections.
 	if len(actIRIs) >= 1 {
 		iri = actIRIs[0]
 	}
@@ -403,6 +628,55 @@ func (c C) toCollections(ctx context.Context, act vocab.Item, colIRI ...vocab.IR
 	return iri, it, nil
 }
 
+// CollectionResult is the outcome of posting an activity to one of the collections passed to [C.ToCollections].
+type CollectionResult struct {
+	// Collection is the IRI of the collection the activity has been posted to.
+	Collection vocab.IRI
+	// IRI is the IRI of the created activity, from the Location header of the response.
+	IRI vocab.IRI
+	// Item is the item returned in the response body, if any.
+	Item vocab.Item
+	// Status is the response status, zero if the request didn't complete.
+	Status int
+	Err    error
+}
+
+// ToCollections posts the act activity to every one of the colIRI collections, and it returns the result of each
+// of them, in the same order. Unlike [C.CtxToCollection], a failure doesn't stop the posting to the rest of
+// the collections. The collections are posted to concurrently if the client has been configured with [WithParallelPosting].
+func (c C) ToCollections(ctx context.Context, act vocab.Item, colIRI ...vocab.IRI) []CollectionResult {
+	results := make([]CollectionResult, len(colIRI))
+	cont, err := jsonld.WithContext(jsonld.IRI(vocab.ActivityBaseURI), jsonld.IRI(vocab.SecurityContextURI)).Marshal(act)
+
+	post := func(r *CollectionResult) {
+		switch {
+		case err != nil:
+			r.Err = errf("unable to marshal activity").iri(r.Collection).annotate(err)
+		case len(r.Collection) == 0:
+			r.Err = errf("invalid IRI to POST to")
+		default:
+			r.Status, r.IRI, r.Item, r.Err = c.postTo(ctx, cont, r.Collection)
+		}
+	}
+
+	slots := make(chan struct{}, max(c.postWorkers, 1))
+	wg := sync.WaitGroup{}
+	for i, iri := range colIRI {
+		results[i].Collection = iri
+		if c.postWorkers <= 1 {
+			post(&results[i])
+			continue
+		}
+		slots <- struct{}{}
+		wg.Go(func() {
+			defer func() { <-slots }()
+			post(&results[i])
+		})
+	}
+	wg.Wait()
+	return results
+}
+
 func (c C) toCollection(ctx context.Context, act vocab.Item, colIRI vocab.IRI) (vocab.IRI, vocab.Item, error) {
 	if len(colIRI) == 0 {
 		return "", nil, errf("invalid IRI to POST to")
@@ -413,19 +687,33 @@ func (c C) toCollection(ctx context.Context, act vocab.Item, colIRI vocab.IRI) (
 		return "", nil, errf("unable to marshal activity").iri(colIRI)
 	}
 
+	_, iri, it, err := c.postTo(ctx, cont, colIRI)
+	return iri, it, err
+}
+
+// postTo sends the cont document to the colIRI collection, and it returns the status of the response
+// together with the Location IRI and the item that the server returned.
+func (c C) postTo(ctx context.Context, cont []byte, colIRI vocab.IRI) (int, vocab.IRI, vocab.Item, error) {
 	req, err := ActivityPubRequest(ctx, string(colIRI), requests.ContentTypeJsonActivity, bytes.NewReader(cont))
 	if err != nil {
-		return "", nil, err
+		return 0, "", nil, err
 	}
 
 	resp, err := c.Do(req)
 	if err != nil {
-		return "", nil, err
+		return 0, "", nil, err
 	}
+	iri, it, err := c.postResult(colIRI, resp)
+	return resp.StatusCode, iri, it, err
+}
 
+// postResult decodes the response of a POST request to the colIRI, returning the Location IRI and the item
+// that the server returned.
+func (c C) postResult(colIRI vocab.IRI, resp *http.Response) (vocab.IRI, vocab.Item, error) {
 	resultIRI := vocab.IRI(resp.Header.Get("Location"))
 
 	if resp.StatusCode >= http.StatusBadRequest && resp.StatusCode != http.StatusGone {
+		var err error
 		if err = errors.FromResponse(resp); err == nil {
 			err = errf("invalid status received: %d", resp.StatusCode).iri(resultIRI)
 		} else {
@@ -13,6 +13,8 @@ import (
 	"reflect"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 	"unsafe"
@@ -585,6 +587,170 @@ func TestC_toCollection(t *testing.T) {
 	}
 }
 
+func TestC_ToCollection_withAuthorizationRetry(t *testing.T) {
+	var bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		if r.Header.Get("Authorization") == "first" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Location", "http://example.com/~jdoe/outbox/1")
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+
+	c := C{c: srv.Client(), l: lw.Dev(lw.SetOutput(t.Output()))}
+	WithAuthorizationFn(mockAuthFn("first"), mockAuthFn("second"))(&c)
+
+	act := &vocab.Activity{Type: vocab.LikeType, Actor: vocab.IRI("http://example.com/~jdoe"), Object: vocab.IRI("http://example.com/notes/1")}
+	iri, _, err := c.ToCollection(act, "http://example.com/~jdoe/outbox")
+	if err != nil {
+		t.Fatalf("ToCollection() error = %s", err)
+	}
+	if iri != "http://example.com/~jdoe/outbox/1" {
+		t.Errorf("ToCollection() IRI = %s", iri)
+	}
+	if len(bodies) != 2 {
+		t.Fatalf("ToCollection() sent %d requests, want 2", len(bodies))
+	}
+	if len(bodies[0]) == 0 || bodies[0] != bodies[1] {
+		t.Errorf("ToCollection() request bodies differ between the authorization attempts: %q, %q", bodies[0], bodies[1])
+	}
+}
+
+func TestC_ToCollections(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/~jdoe/outbox":
+			w.Header().Set("Location", "http://example.com/~jdoe/outbox/1")
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"id":"http://example.com/~jdoe/outbox/1","type":"Like"}`))
+		case "/~alice/outbox":
+			w.Header().Set("Location", "http://example.com/~alice/outbox/1")
+			w.WriteHeader(http.StatusCreated)
+		default:
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer srv.Close()
+
+	type result struct {
+		Collection vocab.IRI
+		IRI        vocab.IRI
+		Item       vocab.Item
+		Status     int
+		Err        bool
+	}
+	allResults := []result{
+		{Collection: "http://example.com/~bob/outbox", Status: http.StatusForbidden, Err: true},
+		{
+			Collection: "http://example.com/~jdoe/outbox",
+			IRI:        "http://example.com/~jdoe/outbox/1",
+			Item:       &vocab.Activity{ID: "http://example.com/~jdoe/outbox/1", Type: vocab.LikeType},
+			Status:     http.StatusCreated,
+		},
+		{Err: true},
+		{Collection: "http://example.com/~alice/outbox", IRI: "http://example.com/~alice/outbox/1", Status: http.StatusCreated},
+	}
+	tests := []struct {
+		name    string
+		workers int
+		act     vocab.Item
+		colIRI  vocab.IRIs
+		want    []result
+	}{
+		{
+			name: "empty",
+			act:  &vocab.Activity{Type: vocab.LikeType},
+		},
+		{
+			name:   "every result is returned",
+			act:    &vocab.Activity{Type: vocab.LikeType},
+			colIRI: vocab.IRIs{"http://example.com/~bob/outbox", "http://example.com/~jdoe/outbox", "", "http://example.com/~alice/outbox"},
+			want:   allResults,
+		},
+		{
+			name:    "every result is returned in order when posting concurrently",
+			workers: 3,
+			act:     &vocab.Activity{Type: vocab.LikeType},
+			colIRI:  vocab.IRIs{"http://example.com/~bob/outbox", "http://example.com/~jdoe/outbox", "", "http://example.com/~alice/outbox"},
+			want:    allResults,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := C{c: srv.Client(), l: lw.Dev(lw.SetOutput(t.Output())), postWorkers: tt.workers}
+
+			var got []result
+			for _, r := range c.ToCollections(context.Background(), tt.act, tt.colIRI...) {
+				got = append(got, result{Collection: r.Collection, IRI: r.IRI, Item: r.Item, Status: r.Status, Err: r.Err != nil})
+			}
+			if !cmp.Equal(got, tt.want, EquateItems) {
+				t.Errorf("ToCollections() = %s", cmp.Diff(tt.want, got, EquateItems))
+			}
+		})
+	}
+}
+
+func TestWithParallelPosting(t *testing.T) {
+	const workers = 2
+
+	var running, peak atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+
+		if strings.HasPrefix(r.URL.Path, "/~bob") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		iri := "http://example.com" + r.URL.Path + "/1"
+		w.Header().Set("Location", iri)
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"id":"` + iri + `","type":"Like"}`))
+	}))
+	defer srv.Close()
+
+	c := C{c: srv.Client(), l: lw.Dev(lw.SetOutput(t.Output()))}
+	WithParallelPosting(workers)(&c)
+
+	act := &vocab.Activity{Type: vocab.LikeType}
+	colIRI := vocab.IRIs{
+		"http://example.com/~bob/outbox",
+		"http://example.com/~alice/outbox",
+		"http://example.com/~bob/inbox",
+		"http://example.com/~jdoe/outbox",
+		"http://example.com/~jane/outbox",
+	}
+	_, got, err := c.CtxToCollection(context.Background(), act, colIRI...)
+	if err == nil {
+		t.Fatalf("CtxToCollection() expected an error")
+	}
+	if n := strings.Count(err.Error(), "invalid status received"); n != 2 {
+		t.Errorf("CtxToCollection() error = %s, should contain the errors of both failed collections", err)
+	}
+	want := vocab.ItemCollection{
+		&vocab.Activity{ID: "http://example.com/~alice/outbox/1", Type: vocab.LikeType},
+		&vocab.Activity{ID: "http://example.com/~jdoe/outbox/1", Type: vocab.LikeType},
+		&vocab.Activity{ID: "http://example.com/~jane/outbox/1", Type: vocab.LikeType},
+	}
+	if !cmp.Equal(got, want, EquateItems) {
+		t.Errorf("CtxToCollection() = %s", cmp.Diff(want, got, EquateItems))
+	}
+	if p := peak.Load(); p > workers {
+		t.Errorf("CtxToCollection() posted to %d collections concurrently, want at most %d", p, workers)
+	}
+}
+
 func TestC_LoadIRI(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -866,3 +1032,262 @@ func TestC_Do(t *testing.T) {
 		})
 	}
 }
+
+func TestWithBatchLimits(t *testing.T) {
+	tests := []struct {
+		name     string
+		total    int
+		perHost  int
+		wantHost bool
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name:  "only total",
+			total: 4,
+		},
+		{
+			name:     "total and per host",
+			total:    4,
+			perHost:  2,
+			wantHost: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cl := new(C)
+
+			WithBatchLimits(tt.total, tt.perHost)(cl)
+			if cl.batchSize != tt.total {
+				t.Errorf("WithBatchLimits() total = %d, want %d", cl.batchSize, tt.total)
+			}
+			if (cl.batchHost != nil) != tt.wantHost {
+				t.Errorf("WithBatchLimits() per host limiter = %v, want %t", cl.batchHost, tt.wantHost)
+			}
+		})
+	}
+}
+
+func TestC_LoadIRIs(t *testing.T) {
+	tests := []struct {
+		name    string
+		iris    vocab.IRIs
+		cancel  bool
+		want    map[vocab.IRI]LoadResult
+		wantReq int
+	}{
+		{
+			name: "empty",
+			want: map[vocab.IRI]LoadResult{},
+		},
+		{
+			name: "multiple with duplicates",
+			iris: vocab.IRIs{"http://example.com/1", "http://example.com/2", "http://example.com/1"},
+			want: map[vocab.IRI]LoadResult{
+				"http://example.com/1": {Item: &vocab.Object{ID: "http://example.com/1", Type: vocab.NoteType}},
+				"http://example.com/2": {Item: &vocab.Object{ID: "http://example.com/2", Type: vocab.NoteType}},
+			},
+			wantReq: 2,
+		},
+		{
+			name: "with errors",
+			iris: vocab.IRIs{"http://example.com/1", "http://example.com/missing"},
+			want: map[vocab.IRI]LoadResult{
+				"http://example.com/1":       {Item: &vocab.Object{ID: "http://example.com/1", Type: vocab.NoteType}},
+				"http://example.com/missing": {Err: errf("invalid status received").status(http.StatusNotFound).iri("http://example.com/missing").annotate(errors.NotFoundf("/missing not found"))},
+			},
+			wantReq: 2,
+		},
+		{
+			name:   "canceled context",
+			iris:   vocab.IRIs{"http://example.com/1"},
+			cancel: true,
+			want: map[vocab.IRI]LoadResult{
+				"http://example.com/1": {Err: context.Canceled},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mu := sync.Mutex{}
+			requests := 0
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				requests++
+				mu.Unlock()
+				if r.URL.Path == "/missing" {
+					errors.HandleError(errors.NotFoundf("/missing not found")).ServeHTTP(w, r)
+					return
+				}
+				raw, _ := vocab.MarshalJSON(&vocab.Object{ID: vocab.IRI("http://example.com" + r.URL.Path), Type: vocab.NoteType})
+				w.WriteHeader(http.StatusOK)
+				_, _ = w.Write(raw)
+			}))
+			defer srv.Close()
+
+			c := New(WithHTTPClient(srv.Client()), WithLogger(lw.Dev(lw.SetOutput(t.Output()))), WithBatchLimits(2, 1))
+
+			ctx, cancelFn := context.WithCancel(context.Background())
+			defer cancelFn()
+			if tt.cancel {
+				cancelFn()
+			}
+
+			got := c.LoadIRIs(ctx, tt.iris...)
+			if !cmp.Equal(got, tt.want, EquateItems, EquateWeakErrors(srv.URL)) {
+				t.Errorf("LoadIRIs() got = %s", cmp.Diff(tt.want, got, EquateItems, EquateWeakErrors(srv.URL)))
+			}
+			if requests != tt.wantReq {
+				t.Errorf("LoadIRIs() requests = %d, want %d", requests, tt.wantReq)
+			}
+		})
+	}
+}
+
+func TestC_LoadIRIs_busyHost(t *testing.T) {
+	release := make(chan struct{})
+	served := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Host == "busy.example.com" {
+			<-release
+		} else {
+			close(served)
+		}
+		raw, _ := vocab.MarshalJSON(&vocab.Object{ID: vocab.IRI("http://" + r.Host + r.URL.Path), Type: vocab.NoteType})
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(raw)
+	}))
+	defer srv.Close()
+
+	c := New(WithHTTPClient(srv.Client()), WithLogger(lw.Dev(lw.SetOutput(t.Output()))), WithBatchLimits(2, 1))
+
+	iris := vocab.IRIs{"http://other.example.com/1"}
+	for i := range 8 {
+		iris = append(iris, vocab.IRI(fmt.Sprintf("http://busy.example.com/%d", i)))
+	}
+	done := make(chan map[vocab.IRI]LoadResult)
+	go func() {
+		done <- c.LoadIRIs(context.Background(), iris...)
+	}()
+
+	select {
+	case <-served:
+	case <-time.After(time.Second):
+		t.Errorf("LoadIRIs() didn't load the other host while the busy one was blocked")
+	}
+	close(release)
+	for iri, res := range <-done {
+		if res.Err != nil {
+			t.Errorf("LoadIRIs() %s error = %s", iri, res.Err)
+		}
+	}
+}
+
+func TestC_LoadIRI_concurrent(t *testing.T) {
+	release := make(chan struct{})
+	mu := sync.Mutex{}
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		mu.Unlock()
+		<-release
+		raw, _ := vocab.MarshalJSON(&vocab.Object{ID: "http://example.com/1", Type: vocab.NoteType})
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(raw)
+	}))
+	defer srv.Close()
+
+	c := New(WithHTTPClient(srv.Client()), WithLogger(lw.Dev(lw.SetOutput(t.Output()))))
+
+	count := 5
+	results := make([]vocab.Item, count)
+	wg := sync.WaitGroup{}
+	for i := 0; i < count; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			it, err := c.CtxLoadIRI(context.Background(), "http://example.com/1")
+			if err != nil {
+				t.Errorf("CtxLoadIRI() error = %s", err)
+			}
+			results[i] = it
+		}()
+	}
+	// NOTE(marius): give all the goroutines the chance to start waiting for the first request
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if requests != 1 {
+		t.Errorf("CtxLoadIRI() requests = %d, want 1", requests)
+	}
+	for i, it := range results {
+		if it != results[0] {
+			t.Errorf("CtxLoadIRI() result %d is not the shared item: %v", i, it)
+		}
+	}
+
+	// NOTE(marius): a new load, after the previous ones finished, does its own request
+	if _, err := c.CtxLoadIRI(context.Background(), "http://example.com/1"); err != nil {
+		t.Errorf("CtxLoadIRI() error = %s", err)
+	}
+	if requests != 2 {
+		t.Errorf("CtxLoadIRI() requests = %d, want 2", requests)
+	}
+}
+
+func TestC_LoadIRI_concurrentCancel(t *testing.T) {
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		started <- struct{}{}
+		select {
+		case <-release:
+		case <-r.Context().Done():
+			return
+		}
+		raw, _ := vocab.MarshalJSON(&vocab.Object{ID: "http://example.com/1", Type: vocab.NoteType})
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(raw)
+	}))
+	defer srv.Close()
+
+	c := New(WithHTTPClient(srv.Client()), WithLogger(lw.Dev(lw.SetOutput(t.Output()))))
+
+	firstCtx, cancelFirst := context.WithCancel(context.Background())
+	firstErr := make(chan error, 1)
+	go func() {
+		_, err := c.CtxLoadIRI(firstCtx, "http://example.com/1")
+		firstErr <- err
+	}()
+	<-started
+
+	second := make(chan vocab.Item, 1)
+	go func() {
+		it, err := c.CtxLoadIRI(context.Background(), "http://example.com/1")
+		if err != nil {
+			t.Errorf("CtxLoadIRI() error = %s", err)
+		}
+		second <- it
+	}()
+	// NOTE(marius): give the second load the chance to start waiting for the first request
+	time.Sleep(50 * time.Millisecond)
+
+	cancelFirst()
+	if err := <-firstErr; !errors.Is(err, context.Canceled) {
+		t.Errorf("CtxLoadIRI() error = %v, want %s", err, context.Canceled)
+	}
+	close(release)
+
+	want := &vocab.Object{ID: "http://example.com/1", Type: vocab.NoteType}
+	if got := <-second; !cmp.Equal(got, want, EquateItems) {
+		t.Errorf("CtxLoadIRI() = %s", cmp.Diff(want, got, EquateItems))
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("CtxLoadIRI() requests = %d, want 1", n)
+	}
+}
@@ -0,0 +1,258 @@
+package client
+
+import (
+	"context"
+
+	vocab "github.com/go-ap/activitypub"
+	"github.com/go-ap/errors"
+	"github.com/go-ap/jsonld"
+)
+
+// DeliveryResult is the outcome of delivering an activity to one inbox.
+type DeliveryResult struct {
+	// Inbox is the inbox the activity has been posted to. It is empty if the Recipients couldn't be resolved.
+	Inbox vocab.IRI
+	// Recipients are the actors for which the activity was delivered to the Inbox, more than one
+	// when the Inbox is a shared inbox.
+	Recipients vocab.IRIs
+	// Status is the response status of the Inbox server, zero if the request didn't complete.
+	Status int
+	Err    error
+}
+
+// Deliver posts the act activity to the inboxes of its recipients, as ActivityPub federated servers do.
+//
+// The recipients are the actors found in the to, cc, bto, bcc and audience properties of the activity,
+// with the collections among them, like the followers of an actor, replaced by their members.
+// The activity is posted only once to every inbox, and the shared inbox of the actors which have one is used
+// instead of their own. The bto and bcc properties are removed from the activity, and from its object, before
+// it is posted, so the actors that are addressed only through them get it in their own inbox, as the server
+// of a shared inbox wouldn't know to deliver it to them.
+//
+// It returns a [DeliveryResult] for every inbox, and for every recipient that couldn't be resolved to one.
+//
+// https://www.w3.org/TR/activitypub/#delivery
+func (c C) Deliver(ctx context.Context, act vocab.Item) ([]DeliveryResult, error) {
+	recipients, blind, actors, err := deliveryRecipients(act)
+	if err != nil {
+		return nil, err
+	}
+	stripped, err := stripBlindRecipients(act)
+	if err != nil {
+		return nil, err
+	}
+	cont, err := jsonld.WithContext(jsonld.IRI(vocab.ActivityBaseURI), jsonld.IRI(vocab.SecurityContextURI)).Marshal(stripped)
+	if err != nil {
+		return nil, errf("unable to marshal activity").iri(act.GetLink()).annotate(err)
+	}
+
+	results := c.deliveryTargets(ctx, recipients, blind, actors)
+	for i, r := range results {
+		if len(r.Inbox) == 0 {
+			continue
+		}
+		results[i].Status, _, _, results[i].Err = c.postTo(ctx, cont, r.Inbox)
+	}
+	return results, nil
+}
+
+// deliveryRecipients returns the IRIs of the recipients of the act activity, the ones among them which are addressed
+// only through the bto and bcc properties, and the IRIs of its actors.
+func deliveryRecipients(act vocab.Item) (vocab.IRIs, vocab.IRIs, vocab.IRIs, error) {
+	if vocab.IsNil(act) {
+		return nil, nil, nil, errf("invalid nil activity")
+	}
+	var recipients, blind, actors vocab.IRIs
+	err := vocab.OnIntransitiveActivity(act, func(a *vocab.IntransitiveActivity) error {
+		actors = itemIRIs(a.Actor)
+		var visible vocab.IRIs
+		for _, col := range []vocab.ItemCollection{a.To, a.CC, a.Audience, a.Bto, a.BCC} {
+			for _, iri := range itemIRIs(col) {
+				// NOTE(marius): the public collection doesn't have an inbox, and we don't deliver to ourselves
+				if iri.Equal(vocab.PublicNS) || actors.Contains(iri) || recipients.Contains(iri) {
+					continue
+				}
+				recipients = append(recipients, iri)
+			}
+		}
+		for _, col := range []vocab.ItemCollection{a.To, a.CC, a.Audience} {
+			visible = append(visible, itemIRIs(col)...)
+		}
+		for _, iri := range recipients {
+			if !visible.Contains(iri) {
+				blind = append(blind, iri)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, nil, errors.Annotatef(err, "object of type %T is not an activity", act)
+	}
+	return recipients, blind, actors, nil
+}
+
+func itemIRIs(it vocab.Item) vocab.IRIs {
+	var iris vocab.IRIs
+	if vocab.IsNil(it) {
+		return iris
+	}
+	_ = vocab.OnItem(it, func(it vocab.Item) error {
+		if iri := it.GetLink(); len(iri) > 0 {
+			iris = append(iris, iri)
+		}
+		return nil
+	})
+	return iris
+}
+
+// stripBlindRecipients returns a copy of the act activity, and of its object, without the bto and bcc properties.
+func stripBlindRecipients(act vocab.Item) (vocab.Item, error) {
+	switch act.(type) {
+	case *vocab.Activity, *vocab.IntransitiveActivity:
+		return stripObjectBlindRecipients(act), nil
+	}
+	return nil, errf("unable to remove the blind recipients of %T", act).iri(act.GetLink())
+}
+
+// stripObjectBlindRecipients returns a copy of the it object, or of the objects of the it collection, without
+// the bto and bcc properties. If it's an activity, its object gets stripped as well.
+func stripObjectBlindRecipients(it vocab.Item) vocab.Item {
+	if vocab.IsNil(it) || vocab.IsIRI(it) {
+		return it
+	}
+	if vocab.IsItemCollection(it) {
+		col := make(vocab.ItemCollection, 0)
+		_ = vocab.OnItemCollection(it, func(items *vocab.ItemCollection) error {
+			for _, ob := range *items {
+				col = append(col, stripObjectBlindRecipients(ob))
+			}
+			return nil
+		})
+		return col
+	}
+	cp := shallowCopy(it)
+	if cp == nil {
+		return it
+	}
+	_ = vocab.OnObject(cp, func(o *vocab.Object) error {
+		o.Bto, o.BCC = nil, nil
+		return nil
+	})
+	if a, ok := cp.(*vocab.Activity); ok {
+		a.Object = stripObjectBlindRecipients(a.Object)
+	}
+	return cp
+}
+
+// deliveryTargets resolves the recipients to the inboxes where the activity needs to be posted,
+// skipping the actors, which are the authors of the activity. The blind recipients, and the members
+// of the blind collections, which are not addressed otherwise, get the activity in their own inbox.
+func (c C) deliveryTargets(ctx context.Context, recipients, blind, actors vocab.IRIs) []DeliveryResult {
+	var results []DeliveryResult
+	failed := func(iri vocab.IRI, err error) {
+		results = append(results, DeliveryResult{Recipients: vocab.IRIs{iri}, Err: err})
+	}
+
+	var found []vocab.Item
+	var members vocab.IRIs
+	// NOTE(marius): shared marks the actors that are addressed in the properties which are not removed
+	//  from the delivered activity, so the servers of their shared inboxes can find them.
+	shared := make(map[vocab.IRI]bool)
+	reached := func(iri vocab.IRI, through vocab.IRI) {
+		shared[iri] = shared[iri] || !blind.Contains(through)
+	}
+	loaded := c.LoadIRIs(ctx, recipients...)
+	for _, iri := range recipients {
+		it, err := recipient(iri, loaded[iri])
+		switch {
+		case err != nil:
+			failed(iri, err)
+		case vocab.ActorTypes.Match(it.GetType()):
+			found = append(found, it)
+			reached(it.GetLink(), iri)
+		case vocab.CollectionTypes.Match(it.GetType()):
+			// NOTE(marius): we expand only one level of collections, as the specification recommends
+			for it, err := range c.items(ctx, it) {
+				if err != nil {
+					failed(iri, errors.Annotatef(err, "unable to load the members of the collection"))
+					break
+				}
+				if vocab.IsNil(it) {
+					continue
+				}
+				reached(it.GetLink(), iri)
+				switch {
+				case !vocab.IsIRI(it):
+					found = append(found, it)
+				case recipients.Contains(it.GetLink()) || members.Contains(it.GetLink()):
+					// NOTE(marius): the member is also a direct recipient, or a member of another collection
+				default:
+					members = append(members, it.GetLink())
+				}
+			}
+		default:
+			failed(iri, errf("recipient is neither an actor nor a collection").iri(iri))
+		}
+	}
+	loaded = c.LoadIRIs(ctx, members...)
+	for _, iri := range members {
+		it, err := recipient(iri, loaded[iri])
+		switch {
+		case err != nil:
+			failed(iri, err)
+		case vocab.ActorTypes.Match(it.GetType()):
+			found = append(found, it)
+		default:
+			failed(iri, errf("collection member is not an actor").iri(iri))
+		}
+	}
+
+	byInbox := make(map[vocab.IRI]int)
+	for _, it := range found {
+		iri := it.GetLink()
+		if actors.Contains(iri) {
+			continue
+		}
+		in := deliveryInbox(it, shared[iri])
+		if len(in) == 0 {
+			failed(iri, errf("recipient doesn't have an inbox").iri(iri))
+			continue
+		}
+		i, ok := byInbox[in]
+		if !ok {
+			i = len(results)
+			byInbox[in] = i
+			results = append(results, DeliveryResult{Inbox: in})
+		}
+		if !results[i].Recipients.Contains(iri) {
+			results[i].Recipients = append(results[i].Recipients, iri)
+		}
+	}
+	return results
+}
+
+// recipient returns the item loaded for the iri recipient, or the error if it couldn't be loaded.
+func recipient(iri vocab.IRI, res LoadResult) (vocab.Item, error) {
+	if res.Err != nil {
+		return nil, res.Err
+	}
+	if vocab.IsNil(res.Item) {
+		return nil, errf("unable to load recipient").iri(iri)
+	}
+	return res.Item, nil
+}
+
+// deliveryInbox returns the inbox of the it actor, or its shared inbox, if it has one and shared is true.
+func deliveryInbox(it vocab.Item, shared bool) vocab.IRI {
+	var in vocab.IRI
+	_ = vocab.OnActor(it, func(a *vocab.Actor) error {
+		if shared && a.Endpoints != nil && !vocab.IsNil(a.Endpoints.SharedInbox) {
+			in = a.Endpoints.SharedInbox.GetLink()
+		}
+		if len(in) == 0 && !vocab.IsNil(a.Inbox) {
+			in = a.Inbox.GetLink()
+		}
+		return nil
+	})
+	return in
+}
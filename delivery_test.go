@@ -0,0 +1,178 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"git.sr.ht/~mariusor/lw"
+	vocab "github.com/go-ap/activitypub"
+	"github.com/google/go-cmp/cmp"
+)
+
+func mockDeliveryHandler(t *testing.T, posted map[string]int, mu *sync.Mutex) http.HandlerFunc {
+	docs := map[string]string{
+		"/~jdoe":           `{"id":"http://example.com/~jdoe","type":"Person","inbox":"http://example.com/~jdoe/inbox","followers":"http://example.com/~jdoe/followers"}`,
+		"/~jdoe/followers": `{"id":"http://example.com/~jdoe/followers","type":"OrderedCollection","orderedItems":["http://example.com/~alice","http://example.com/~bob","http://example.com/~carol","http://example.com/~jdoe"]}`,
+		"/~alice":          `{"id":"http://example.com/~alice","type":"Person","inbox":"http://example.com/~alice/inbox","endpoints":{"sharedInbox":"http://example.com/inbox"}}`,
+		"/~bob":            `{"id":"http://example.com/~bob","type":"Person","inbox":"http://example.com/~bob/inbox","endpoints":{"sharedInbox":"http://example.com/inbox"}}`,
+		"/~carol":          `{"id":"http://example.com/~carol","type":"Person","inbox":"http://example.com/~carol/inbox"}`,
+		"/~dave":           `{"id":"http://example.com/~dave","type":"Person","inbox":"http://example.com/~dave/inbox"}`,
+		"/~erin":           `{"id":"http://example.com/~erin","type":"Person","inbox":"http://example.com/~erin/inbox","endpoints":{"sharedInbox":"http://example.com/inbox"}}`,
+		"/~jdoe/friends":   `{"id":"http://example.com/~jdoe/friends","type":"OrderedCollection","orderedItems":["http://example.com/notes/1","http://example.com/~dave"]}`,
+		"/notes/1":         `{"id":"http://example.com/notes/1","type":"Note"}`,
+	}
+	loaded := make(map[string]int)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			body, _ := io.ReadAll(r.Body)
+			if bytes.Contains(body, []byte(`"bto"`)) || bytes.Contains(body, []byte(`"bcc"`)) {
+				t.Errorf("blind recipients were not removed from the delivered activity: %s", body)
+			}
+			mu.Lock()
+			posted[r.URL.Path]++
+			mu.Unlock()
+			if r.URL.Path == "/~carol/inbox" {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		mu.Lock()
+		loaded[r.URL.Path]++
+		if loaded[r.URL.Path] > 1 {
+			t.Errorf("%s has been loaded more than once", r.URL.Path)
+		}
+		mu.Unlock()
+		doc, ok := docs[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", ContentTypeJsonActivity)
+		_, _ = w.Write([]byte(doc))
+	}
+}
+
+func TestC_Deliver(t *testing.T) {
+	type result struct {
+		Inbox      vocab.IRI
+		Recipients vocab.IRIs
+		Status     int
+		Err        bool
+	}
+	tests := []struct {
+		name       string
+		act        vocab.Item
+		want       []result
+		wantErr    bool
+		wantPosted map[string]int
+	}{
+		{
+			name:    "nil activity",
+			wantErr: true,
+		},
+		{
+			name:    "not an activity",
+			act:     &vocab.Object{ID: "http://example.com/notes/1", Type: vocab.NoteType},
+			wantErr: true,
+		},
+		{
+			name: "followers, shared inboxes and blind recipients",
+			act: &vocab.Activity{
+				Type:  vocab.CreateType,
+				Actor: vocab.IRI("http://example.com/~jdoe"),
+				To:    vocab.ItemCollection{vocab.PublicNS},
+				CC:    vocab.ItemCollection{vocab.IRI("http://example.com/~jdoe/followers")},
+				Bto:   vocab.ItemCollection{vocab.IRI("http://example.com/~missing")},
+				BCC:   vocab.ItemCollection{vocab.IRI("http://example.com/~dave"), vocab.IRI("http://example.com/~bob")},
+				Object: &vocab.Object{
+					Type: vocab.NoteType,
+					BCC:  vocab.ItemCollection{vocab.IRI("http://example.com/~dave")},
+				},
+			},
+			want: []result{
+				{Recipients: vocab.IRIs{"http://example.com/~missing"}, Err: true},
+				{Inbox: "http://example.com/~dave/inbox", Recipients: vocab.IRIs{"http://example.com/~dave"}, Status: http.StatusAccepted},
+				{Inbox: "http://example.com/inbox", Recipients: vocab.IRIs{"http://example.com/~bob", "http://example.com/~alice"}, Status: http.StatusAccepted},
+				{Inbox: "http://example.com/~carol/inbox", Recipients: vocab.IRIs{"http://example.com/~carol"}, Status: http.StatusInternalServerError, Err: true},
+			},
+			wantPosted: map[string]int{"/~dave/inbox": 1, "/inbox": 1, "/~carol/inbox": 1},
+		},
+		{
+			name: "blind recipients with shared inboxes",
+			act: &vocab.Activity{
+				Type:  vocab.CreateType,
+				Actor: vocab.IRI("http://example.com/~jdoe"),
+				To:    vocab.ItemCollection{vocab.IRI("http://example.com/~alice")},
+				BCC:   vocab.ItemCollection{vocab.IRI("http://example.com/~erin")},
+				Object: &vocab.Activity{
+					Type:   vocab.UpdateType,
+					BCC:    vocab.ItemCollection{vocab.IRI("http://example.com/~erin")},
+					Object: &vocab.Actor{Type: vocab.PersonType, Bto: vocab.ItemCollection{vocab.IRI("http://example.com/~erin")}},
+				},
+			},
+			want: []result{
+				{Inbox: "http://example.com/inbox", Recipients: vocab.IRIs{"http://example.com/~alice"}, Status: http.StatusAccepted},
+				{Inbox: "http://example.com/~erin/inbox", Recipients: vocab.IRIs{"http://example.com/~erin"}, Status: http.StatusAccepted},
+			},
+			wantPosted: map[string]int{"/inbox": 1, "/~erin/inbox": 1},
+		},
+		{
+			name: "collection member which is not an actor",
+			act: &vocab.Activity{
+				Type:  vocab.CreateType,
+				Actor: vocab.IRI("http://example.com/~jdoe"),
+				CC:    vocab.ItemCollection{vocab.IRI("http://example.com/~jdoe/friends")},
+			},
+			want: []result{
+				{Recipients: vocab.IRIs{"http://example.com/notes/1"}, Err: true},
+				{Inbox: "http://example.com/~dave/inbox", Recipients: vocab.IRIs{"http://example.com/~dave"}, Status: http.StatusAccepted},
+			},
+			wantPosted: map[string]int{"/~dave/inbox": 1},
+		},
+		{
+			name: "recipient which is not an actor",
+			act: &vocab.IntransitiveActivity{
+				Type:     vocab.ArriveType,
+				Actor:    vocab.IRI("http://example.com/~jdoe"),
+				Audience: vocab.ItemCollection{vocab.IRI("http://example.com/notes/1"), vocab.IRI("http://example.com/~carol")},
+			},
+			want: []result{
+				{Recipients: vocab.IRIs{"http://example.com/notes/1"}, Err: true},
+				{Inbox: "http://example.com/~carol/inbox", Recipients: vocab.IRIs{"http://example.com/~carol"}, Status: http.StatusInternalServerError, Err: true},
+			},
+			wantPosted: map[string]int{"/~carol/inbox": 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mu := sync.Mutex{}
+			posted := make(map[string]int)
+			srv := httptest.NewServer(mockDeliveryHandler(t, posted, &mu))
+			defer srv.Close()
+
+			c := C{c: srv.Client(), l: lw.Dev(lw.SetOutput(t.Output())), inflight: newInflight()}
+			res, err := c.Deliver(context.Background(), tt.act)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Deliver() error = %v, wantErr %t", err, tt.wantErr)
+			}
+
+			var got []result
+			for _, r := range res {
+				got = append(got, result{Inbox: r.Inbox, Recipients: r.Recipients, Status: r.Status, Err: r.Err != nil})
+			}
+			if !cmp.Equal(got, tt.want) {
+				t.Errorf("Deliver() = %s", cmp.Diff(tt.want, got))
+			}
+			if len(tt.wantPosted) > 0 && !cmp.Equal(posted, tt.wantPosted) {
+				t.Errorf("Deliver() posted = %s", cmp.Diff(tt.wantPosted, posted))
+			}
+		})
+	}
+}
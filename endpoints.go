@@ -0,0 +1,169 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sync"
+
+	vocab "github.com/go-ap/activitypub"
+	"github.com/go-ap/errors"
+)
+
+// Endpoints are the endpoints that the server of an actor advertises in the actor's "endpoints" property.
+type Endpoints struct {
+	// ProxyURL is the endpoint that dereferences IRIs which require authorization on the actor's behalf.
+	ProxyURL vocab.IRI `json:"proxyUrl,omitempty"`
+	// UploadMedia is the endpoint where media files can be uploaded.
+	UploadMedia vocab.IRI `json:"uploadMedia,omitempty"`
+	// OauthAuthorizationEndpoint is the endpoint where clients can obtain an OAuth2 authorization grant.
+	OauthAuthorizationEndpoint vocab.IRI `json:"oauthAuthorizationEndpoint,omitempty"`
+	// OauthTokenEndpoint is the endpoint where clients can obtain OAuth2 access tokens.
+	OauthTokenEndpoint vocab.IRI `json:"oauthTokenEndpoint,omitempty"`
+}
+
+// UnmarshalJSON decodes the endpoints, which can be either IRIs or objects that have an id.
+// If data is the IRI of a separate endpoints document, the endpoints are left empty, [C.ActorEndpoints]
+// loads that document.
+func (e *Endpoints) UnmarshalJSON(data []byte) error {
+	if endpointsLink(data) != "" {
+		return nil
+	}
+	raw := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	e.ProxyURL = endpointIRI(raw["proxyUrl"])
+	e.UploadMedia = endpointIRI(raw["uploadMedia"])
+	e.OauthAuthorizationEndpoint = endpointIRI(raw["oauthAuthorizationEndpoint"])
+	e.OauthTokenEndpoint = endpointIRI(raw["oauthTokenEndpoint"])
+	return nil
+}
+
+// endpointsLink returns the IRI of the endpoints document, if the endpoints property is a link to one.
+func endpointsLink(data []byte) vocab.IRI {
+	var iri string
+	if err := json.Unmarshal(data, &iri); err != nil {
+		return ""
+	}
+	return vocab.IRI(iri)
+}
+
+func endpointIRI(raw json.RawMessage) vocab.IRI {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 {
+		return ""
+	}
+	var iri string
+	if err := json.Unmarshal(raw, &iri); err == nil {
+		return vocab.IRI(iri)
+	}
+	var ob struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(raw, &ob); err == nil {
+		return vocab.IRI(ob.ID)
+	}
+	return ""
+}
+
+// WithActor sets the actor on whose behalf the client operates. Its endpoints become available through [C.Endpoints]
+// after they are loaded with [C.DiscoverEndpoints].
+func WithActor(actor vocab.Item) OptionFn {
+	return func(c *C) {
+		c.actor = actor
+	}
+}
+
+// Endpoints returns the endpoints of the actor set with [WithActor], as loaded by [C.DiscoverEndpoints].
+func (c C) Endpoints() Endpoints {
+	return c.endpoints.get()
+}
+
+// ActorEndpoints loads the actor and returns the endpoints it advertises.
+func (c C) ActorEndpoints(ctx context.Context, actor vocab.Item) (Endpoints, error) {
+	e := Endpoints{}
+	if vocab.IsNil(actor) {
+		return e, errors.Newf("nil actor")
+	}
+	// NOTE(marius): we decode the actor document ourselves, because the vocabulary package doesn't know about
+	// the proxyUrl endpoint, so we can't rely on it, even if we have received the full actor.
+	body, _, err := c.fetchDocument(ctx, actor.GetLink().String(), ContentTypeJsonActivity)
+	if err != nil {
+		return e, errors.Annotatef(err, "unable to load actor")
+	}
+	doc := struct {
+		Endpoints json.RawMessage `json:"endpoints"`
+	}{}
+	if err = json.Unmarshal(body, &doc); err != nil {
+		return e, errf("invalid actor document").iri(actor.GetLink()).annotate(err)
+	}
+	raw := bytes.TrimSpace(doc.Endpoints)
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return e, nil
+	}
+	if iri := endpointsLink(raw); len(iri) > 0 {
+		// NOTE(marius): the endpoints can be a separate document, which the actor links to
+		if raw, _, err = c.fetchDocument(ctx, iri.String(), ContentTypeJsonActivity); err != nil {
+			return e, errors.Annotatef(err, "unable to load endpoints")
+		}
+		if len(endpointsLink(raw)) > 0 {
+			return e, errf("invalid endpoints document").iri(iri)
+		}
+	}
+	if err = json.Unmarshal(raw, &e); err != nil {
+		return e, errf("invalid endpoints").iri(actor.GetLink()).annotate(err)
+	}
+	return e, nil
+}
+
+// DiscoverEndpoints loads the actor set with [WithActor], and configures the client, and its copies, with its endpoints.
+// Unless one has been set explicitly with [WithProxyURL], the actor's proxyUrl endpoint is used for
+// dereferencing the IRIs that can't be loaded directly.
+func (c C) DiscoverEndpoints(ctx context.Context) error {
+	if vocab.IsNil(c.actor) {
+		return errf("the client doesn't have an actor")
+	}
+	if c.endpoints == nil {
+		return errf("the client has not been created with New")
+	}
+	e, err := c.ActorEndpoints(ctx, c.actor)
+	if err != nil {
+		return err
+	}
+	c.endpoints.set(e)
+	return nil
+}
+
+// proxyEndpointIRI returns the proxyUrl set with [WithProxyURL], or the one discovered for the actor of the client.
+func (c C) proxyEndpointIRI() vocab.IRI {
+	if len(c.proxyURL) > 0 {
+		return c.proxyURL
+	}
+	return c.endpoints.get().ProxyURL
+}
+
+// actorEndpoints holds the endpoints discovered for the actor of the client, so the copies of the client share them.
+type actorEndpoints struct {
+	mu sync.RWMutex
+	e  Endpoints
+}
+
+func newActorEndpoints() *actorEndpoints {
+	return &actorEndpoints{}
+}
+
+func (a *actorEndpoints) get() Endpoints {
+	if a == nil {
+		return Endpoints{}
+	}
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.e
+}
+
+func (a *actorEndpoints) set(e Endpoints) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.e = e
+}
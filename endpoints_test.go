@@ -0,0 +1,160 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	vocab "github.com/go-ap/activitypub"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestEndpoints_UnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    Endpoints
+		wantErr bool
+	}{
+		{
+			name: "empty",
+			data: `{}`,
+		},
+		{
+			name: "IRIs",
+			data: `{"proxyUrl":"https://example.com/proxy","uploadMedia":"https://example.com/upload","oauthAuthorizationEndpoint":"https://example.com/oauth/authorize","oauthTokenEndpoint":"https://example.com/oauth/token","sharedInbox":"https://example.com/inbox"}`,
+			want: Endpoints{
+				ProxyURL:                   "https://example.com/proxy",
+				UploadMedia:                "https://example.com/upload",
+				OauthAuthorizationEndpoint: "https://example.com/oauth/authorize",
+				OauthTokenEndpoint:         "https://example.com/oauth/token",
+			},
+		},
+		{
+			name: "objects",
+			data: `{"proxyUrl":{"id":"https://example.com/proxy","type":"Link"},"uploadMedia":42}`,
+			want: Endpoints{ProxyURL: "https://example.com/proxy"},
+		},
+		{
+			name: "link to the endpoints document",
+			data: `"https://example.com/~jdoe/endpoints"`,
+		},
+		{
+			name:    "invalid",
+			data:    `["https://example.com/proxy"]`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Endpoints{}
+			if err := json.Unmarshal([]byte(tt.data), &got); (err != nil) != tt.wantErr {
+				t.Fatalf("UnmarshalJSON() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if !cmp.Equal(got, tt.want) {
+				t.Errorf("UnmarshalJSON() = %s", cmp.Diff(tt.want, got))
+			}
+		})
+	}
+}
+
+func TestC_DiscoverEndpoints(t *testing.T) {
+	docs := map[string]string{
+		"/~jdoe":          `{"id":"http://example.com/~jdoe","type":"Person","endpoints":{"proxyUrl":"http://example.com/proxy","uploadMedia":"http://example.com/upload","oauthTokenEndpoint":"http://example.com/oauth/token"}}`,
+		"/~bob":           `{"id":"http://example.com/~bob","type":"Person","endpoints":"http://example.com/~bob/endpoints"}`,
+		"/~bob/endpoints": `{"id":"http://example.com/~bob/endpoints","uploadMedia":"http://example.com/upload"}`,
+		"/~carol":         `{"id":"http://example.com/~carol","type":"Person","endpoints":"http://example.com/~carol/endpoints"}`,
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		doc, ok := docs[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", ContentTypeJsonActivity)
+		_, _ = w.Write([]byte(doc))
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name         string
+		opts         []OptionFn
+		want         Endpoints
+		wantProxyURL vocab.IRI
+		wantErr      bool
+	}{
+		{
+			name:    "without actor",
+			wantErr: true,
+		},
+		{
+			name: "actor IRI",
+			opts: []OptionFn{WithActor(vocab.IRI("http://example.com/~jdoe"))},
+			want: Endpoints{
+				ProxyURL:           "http://example.com/proxy",
+				UploadMedia:        "http://example.com/upload",
+				OauthTokenEndpoint: "http://example.com/oauth/token",
+			},
+			wantProxyURL: "http://example.com/proxy",
+		},
+		{
+			name: "actor is loaded to get its proxyUrl",
+			opts: []OptionFn{WithActor(&vocab.Actor{ID: "http://example.com/~jdoe", Type: vocab.PersonType})},
+			want: Endpoints{
+				ProxyURL:           "http://example.com/proxy",
+				UploadMedia:        "http://example.com/upload",
+				OauthTokenEndpoint: "http://example.com/oauth/token",
+			},
+			wantProxyURL: "http://example.com/proxy",
+		},
+		{
+			name: "explicit proxyUrl takes precedence",
+			opts: []OptionFn{WithProxyURL("http://example.com/other-proxy"), WithActor(vocab.IRI("http://example.com/~jdoe"))},
+			want: Endpoints{
+				ProxyURL:           "http://example.com/proxy",
+				UploadMedia:        "http://example.com/upload",
+				OauthTokenEndpoint: "http://example.com/oauth/token",
+			},
+			wantProxyURL: "http://example.com/other-proxy",
+		},
+		{
+			name: "endpoints in a separate document",
+			opts: []OptionFn{WithActor(vocab.IRI("http://example.com/~bob"))},
+			want: Endpoints{UploadMedia: "http://example.com/upload"},
+		},
+		{
+			name:    "endpoints document that can't be loaded",
+			opts:    []OptionFn{WithActor(vocab.IRI("http://example.com/~carol"))},
+			wantErr: true,
+		},
+		{
+			name:    "missing actor",
+			opts:    []OptionFn{WithActor(vocab.IRI("http://example.com/~alice"))},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(append([]OptionFn{WithHTTPClient(srv.Client())}, tt.opts...)...)
+			if got := c.Endpoints(); !cmp.Equal(got, Endpoints{}) {
+				t.Errorf("New() loaded the endpoints = %v", got)
+			}
+			cp := *c
+			if err := c.DiscoverEndpoints(context.Background()); (err != nil) != tt.wantErr {
+				t.Errorf("DiscoverEndpoints() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if got := c.Endpoints(); !cmp.Equal(got, tt.want) {
+				t.Errorf("Endpoints() = %s", cmp.Diff(tt.want, got))
+			}
+			if got := c.proxyEndpointIRI(); got != tt.wantProxyURL {
+				t.Errorf("DiscoverEndpoints() proxyURL = %s, want %s", got, tt.wantProxyURL)
+			}
+			// NOTE(marius): the copies of the client made before the discovery get the endpoints too
+			if got := cp.Endpoints(); !cmp.Equal(got, tt.want) {
+				t.Errorf("Endpoints() of the copy = %s", cmp.Diff(tt.want, got))
+			}
+		})
+	}
+}
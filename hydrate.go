@@ -0,0 +1,288 @@
+package client
+
+import (
+	"context"
+	"slices"
+
+	vocab "github.com/go-ap/activitypub"
+	"github.com/go-ap/errors"
+)
+
+// defaultHydrateLimit is the maximum number of IRIs that a [C.Hydrate] call dereferences,
+// when not configured otherwise.
+const defaultHydrateLimit = 100
+
+// WithHydrateLimit sets the maximum number of IRIs that a single [C.Hydrate] call can dereference.
+func WithHydrateLimit(max int) OptionFn {
+	return func(c *C) {
+		c.hydrateLimit = max
+	}
+}
+
+// slot is the location of a value of a property of the owner object, which can be replaced by the loaded object.
+type slot struct {
+	owner vocab.Item
+	it    vocab.Item
+	set   func(vocab.Item)
+}
+
+// Hydrate dereferences the IRIs found in the props properties of the it [vocab.Item], like "actor", "object",
+// "attributedTo", "inReplyTo", "tag" or "attachment", and it replaces them in place with the loaded objects.
+// It then repeats the process for the properties of the loaded objects, up to depth levels.
+//
+// Every IRI is loaded only once, and all the properties that reference it get the same object, except
+// the ones where that would create a cycle, like the IRIs pointing to the it item itself, which are left as they are.
+// The number of IRIs loaded in a call is limited, see [WithHydrateLimit].
+// The IRIs that failed to load are left in place, and their errors are returned together.
+func (c C) Hydrate(ctx context.Context, it vocab.Item, depth int, props ...string) error {
+	if vocab.IsNil(it) {
+		return errors.Newf("unable to hydrate nil item")
+	}
+	limit := c.hydrateLimit
+	if limit <= 0 {
+		limit = defaultHydrateLimit
+	}
+
+	// NOTE(marius): loaded holds the objects we have already seen, so we can fill in the later references
+	//  to them without loading them again, and links records where we did that, so we don't create cycles.
+	loaded := make(map[vocab.IRI]vocab.Item)
+	if id := it.GetLink(); len(id) > 0 {
+		loaded[id] = it
+	}
+	links := make(map[vocab.Item][]vocab.Item)
+	link := func(s slot, it vocab.Item) {
+		s.set(it)
+		links[s.owner] = append(links[s.owner], it)
+	}
+
+	errs := make([]error, 0)
+	nodes := vocab.ItemCollection{it}
+	if vocab.IsItemCollection(it) {
+		// NOTE(marius): a collection can't be used as a key of links, so we start from its items
+		_ = vocab.OnItemCollection(it, func(col *vocab.ItemCollection) error {
+			nodes = slices.Clone(*col)
+			return nil
+		})
+	}
+	for level := 0; level < depth && len(nodes) > 0; level++ {
+		slots := make([]slot, 0)
+		for _, n := range nodes {
+			for _, prop := range props {
+				for _, s := range propertySlots(n, prop) {
+					s.owner = n
+					slots = append(slots, s)
+				}
+			}
+		}
+
+		next := make(vocab.ItemCollection, 0)
+		toLoad := make(vocab.IRIs, 0)
+		for _, s := range slots {
+			id := s.it.GetLink()
+			if _, ok := loaded[id]; ok || len(id) == 0 {
+				continue
+			}
+			if !vocab.IsIRI(s.it) {
+				// NOTE(marius): the object is already embedded, we only need to look at its properties
+				if cp := shallowCopy(s.it); cp != nil {
+					loaded[id] = cp
+					link(s, cp)
+					next = append(next, cp)
+				}
+				continue
+			}
+			if id.Equal(vocab.PublicNS) || toLoad.Contains(id) {
+				continue
+			}
+			if len(toLoad) == limit {
+				errs = append(errs, errors.Newf("reached the limit of %d IRIs to dereference", limit))
+				break
+			}
+			toLoad = append(toLoad, id)
+		}
+		limit -= len(toLoad)
+
+		// NOTE(marius): the loaded items can be shared with concurrent loads of the same IRIs,
+		//  so we replace the properties on copies of them.
+		results := c.LoadIRIs(ctx, toLoad...)
+		for _, id := range toLoad {
+			res := results[id]
+			if res.Err != nil {
+				errs = append(errs, res.Err)
+				continue
+			}
+			if vocab.IsNil(res.Item) {
+				continue
+			}
+			cp := shallowCopy(res.Item)
+			if cp == nil {
+				loaded[id] = res.Item
+				continue
+			}
+			loaded[id] = cp
+			next = append(next, cp)
+		}
+		for _, s := range slots {
+			if !vocab.IsIRI(s.it) {
+				continue
+			}
+			ob, ok := loaded[s.it.GetLink()]
+			if !ok || reaches(links, ob, s.owner) {
+				continue
+			}
+			link(s, ob)
+		}
+		nodes = next
+		if limit <= 0 {
+			break
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// reaches returns true if the to object can be found by following the links from the from object.
+func reaches(links map[vocab.Item][]vocab.Item, from, to vocab.Item) bool {
+	seen := make(map[vocab.Item]struct{})
+	stack := []vocab.Item{from}
+	for len(stack) > 0 {
+		it := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if it == to {
+			return true
+		}
+		if _, ok := seen[it]; ok {
+			continue
+		}
+		seen[it] = struct{}{}
+		stack = append(stack, links[it]...)
+	}
+	return false
+}
+
+// propertySlots returns the slots of the values of the prop property of the it [vocab.Item].
+func propertySlots(it vocab.Item, prop string) []slot {
+	slots := make([]slot, 0)
+	switch prop {
+	case "actor", "target", "result", "origin", "instrument":
+		_ = vocab.OnIntransitiveActivity(it, func(a *vocab.IntransitiveActivity) error {
+			switch prop {
+			case "actor":
+				slots = itemSlots(a.Actor, func(n vocab.Item) { a.Actor = n })
+			case "target":
+				slots = itemSlots(a.Target, func(n vocab.Item) { a.Target = n })
+			case "result":
+				slots = itemSlots(a.Result, func(n vocab.Item) { a.Result = n })
+			case "origin":
+				slots = itemSlots(a.Origin, func(n vocab.Item) { a.Origin = n })
+			case "instrument":
+				slots = itemSlots(a.Instrument, func(n vocab.Item) { a.Instrument = n })
+			}
+			return nil
+		})
+	case "object":
+		_ = vocab.OnActivity(it, func(a *vocab.Activity) error {
+			slots = itemSlots(a.Object, func(n vocab.Item) { a.Object = n })
+			return nil
+		})
+	default:
+		_ = vocab.OnObject(it, func(o *vocab.Object) error {
+			switch prop {
+			case "attributedTo":
+				slots = itemSlots(o.AttributedTo, func(n vocab.Item) { o.AttributedTo = n })
+			case "inReplyTo":
+				slots = itemSlots(o.InReplyTo, func(n vocab.Item) { o.InReplyTo = n })
+			case "context":
+				slots = itemSlots(o.Context, func(n vocab.Item) { o.Context = n })
+			case "attachment":
+				slots = itemSlots(o.Attachment, func(n vocab.Item) { o.Attachment = n })
+			case "generator":
+				slots = itemSlots(o.Generator, func(n vocab.Item) { o.Generator = n })
+			case "icon":
+				slots = itemSlots(o.Icon, func(n vocab.Item) { o.Icon = n })
+			case "image":
+				slots = itemSlots(o.Image, func(n vocab.Item) { o.Image = n })
+			case "location":
+				slots = itemSlots(o.Location, func(n vocab.Item) { o.Location = n })
+			case "preview":
+				slots = itemSlots(o.Preview, func(n vocab.Item) { o.Preview = n })
+			case "tag":
+				o.Tag = slices.Clone(o.Tag)
+				slots = collectionSlots(o.Tag)
+			case "audience":
+				o.Audience = slices.Clone(o.Audience)
+				slots = collectionSlots(o.Audience)
+			case "to":
+				o.To = slices.Clone(o.To)
+				slots = collectionSlots(o.To)
+			case "cc":
+				o.CC = slices.Clone(o.CC)
+				slots = collectionSlots(o.CC)
+			}
+			return nil
+		})
+	}
+	return slots
+}
+
+func itemSlots(it vocab.Item, set func(vocab.Item)) []slot {
+	if vocab.IsNil(it) {
+		return nil
+	}
+	if vocab.IsItemCollection(it) {
+		// NOTE(marius): we copy the values to a new collection, so we can replace its elements.
+		col := make(vocab.ItemCollection, 0)
+		_ = vocab.OnItemCollection(it, func(items *vocab.ItemCollection) error {
+			col = append(col, *items...)
+			return nil
+		})
+		set(col)
+		return collectionSlots(col)
+	}
+	return []slot{{it: it, set: set}}
+}
+
+func collectionSlots(col vocab.ItemCollection) []slot {
+	slots := make([]slot, 0, len(col))
+	for i := range col {
+		if vocab.IsNil(col[i]) {
+			continue
+		}
+		slots = append(slots, slot{it: col[i], set: func(n vocab.Item) { col[i] = n }})
+	}
+	return slots
+}
+
+// shallowCopy returns a copy of the it item, whose properties can be replaced without changing the original.
+// It returns nil for the types that it doesn't know how to copy.
+func shallowCopy(it vocab.Item) vocab.Item {
+	switch o := it.(type) {
+	case *vocab.Object:
+		cp := *o
+		return &cp
+	case *vocab.Activity:
+		cp := *o
+		return &cp
+	case *vocab.IntransitiveActivity:
+		cp := *o
+		return &cp
+	case *vocab.Actor:
+		cp := *o
+		return &cp
+	case *vocab.Question:
+		cp := *o
+		return &cp
+	case *vocab.Place:
+		cp := *o
+		return &cp
+	case *vocab.Profile:
+		cp := *o
+		return &cp
+	case *vocab.Relationship:
+		cp := *o
+		return &cp
+	case *vocab.Tombstone:
+		cp := *o
+		return &cp
+	}
+	return nil
+}
@@ -0,0 +1,293 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"git.sr.ht/~mariusor/lw"
+	vocab "github.com/go-ap/activitypub"
+	"github.com/go-ap/errors"
+	"github.com/google/go-cmp/cmp"
+)
+
+func mockHydrateObjects() map[string]vocab.Item {
+	return map[string]vocab.Item{
+		"/actors/jdoe": &vocab.Actor{
+			ID:   "http://example.com/actors/jdoe",
+			Type: vocab.PersonType,
+		},
+		"/actors/alice": &vocab.Actor{
+			ID:   "http://example.com/actors/alice",
+			Type: vocab.PersonType,
+		},
+		"/objects/1": &vocab.Object{
+			ID:           "http://example.com/objects/1",
+			Type:         vocab.NoteType,
+			AttributedTo: vocab.IRI("http://example.com/actors/alice"),
+		},
+		"/objects/2": &vocab.Object{
+			ID:           "http://example.com/objects/2",
+			Type:         vocab.NoteType,
+			AttributedTo: vocab.IRI("http://example.com/actors/jdoe"),
+			InReplyTo:    vocab.IRI("http://example.com/objects/1"),
+			Tag:          vocab.ItemCollection{vocab.IRI("http://example.com/tags/go"), vocab.IRI("http://example.com/actors/alice")},
+		},
+		"/objects/3": &vocab.Object{
+			ID:        "http://example.com/objects/3",
+			Type:      vocab.NoteType,
+			InReplyTo: vocab.IRI("http://example.com/objects/3"),
+		},
+		"/objects/4": &vocab.Object{
+			ID:        "http://example.com/objects/4",
+			Type:      vocab.NoteType,
+			InReplyTo: vocab.IRI("http://example.com/objects/5"),
+		},
+		"/objects/5": &vocab.Object{
+			ID:        "http://example.com/objects/5",
+			Type:      vocab.NoteType,
+			InReplyTo: vocab.IRI("http://example.com/objects/4"),
+		},
+	}
+}
+
+func mockCreate(object vocab.IRI) *vocab.Activity {
+	return &vocab.Activity{
+		ID:     "http://example.com/activities/1",
+		Type:   vocab.CreateType,
+		Actor:  vocab.IRI("http://example.com/actors/jdoe"),
+		Object: object,
+		To:     vocab.ItemCollection{vocab.PublicNS},
+	}
+}
+
+func TestC_Hydrate(t *testing.T) {
+	objects := mockHydrateObjects()
+	tests := []struct {
+		name    string
+		it      vocab.Item
+		depth   int
+		props   []string
+		limit   int
+		want    vocab.Item
+		wantErr error
+	}{
+		{
+			name:    "nil",
+			depth:   1,
+			props:   []string{"actor"},
+			wantErr: errors.Newf("unable to hydrate nil item"),
+		},
+		{
+			name:  "depth zero",
+			it:    mockCreate("http://example.com/objects/1"),
+			depth: 0,
+			props: []string{"actor", "object"},
+			want:  mockCreate("http://example.com/objects/1"),
+		},
+		{
+			name:  "actor and object",
+			it:    mockCreate("http://example.com/objects/1"),
+			depth: 1,
+			props: []string{"actor", "object", "to"},
+			want: &vocab.Activity{
+				ID:     "http://example.com/activities/1",
+				Type:   vocab.CreateType,
+				Actor:  objects["/actors/jdoe"],
+				Object: objects["/objects/1"],
+				To:     vocab.ItemCollection{vocab.PublicNS},
+			},
+		},
+		{
+			name:  "nested properties",
+			it:    mockCreate("http://example.com/objects/1"),
+			depth: 2,
+			props: []string{"object", "attributedTo"},
+			want: &vocab.Activity{
+				ID:    "http://example.com/activities/1",
+				Type:  vocab.CreateType,
+				Actor: vocab.IRI("http://example.com/actors/jdoe"),
+				Object: &vocab.Object{
+					ID:           "http://example.com/objects/1",
+					Type:         vocab.NoteType,
+					AttributedTo: objects["/actors/alice"],
+				},
+				To: vocab.ItemCollection{vocab.PublicNS},
+			},
+		},
+		{
+			name:  "objects are loaded only once",
+			it:    mockCreate("http://example.com/objects/2"),
+			depth: 3,
+			props: []string{"actor", "object", "attributedTo", "inReplyTo"},
+			want: &vocab.Activity{
+				ID:    "http://example.com/activities/1",
+				Type:  vocab.CreateType,
+				Actor: objects["/actors/jdoe"],
+				Object: &vocab.Object{
+					ID:           "http://example.com/objects/2",
+					Type:         vocab.NoteType,
+					AttributedTo: objects["/actors/jdoe"],
+					InReplyTo: &vocab.Object{
+						ID:           "http://example.com/objects/1",
+						Type:         vocab.NoteType,
+						AttributedTo: objects["/actors/alice"],
+					},
+					Tag: vocab.ItemCollection{vocab.IRI("http://example.com/tags/go"), vocab.IRI("http://example.com/actors/alice")},
+				},
+				To: vocab.ItemCollection{vocab.PublicNS},
+			},
+		},
+		{
+			name:  "references to loaded objects",
+			it:    mockCreate("http://example.com/objects/2"),
+			depth: 3,
+			props: []string{"object", "inReplyTo", "tag", "attributedTo"},
+			want: &vocab.Activity{
+				ID:    "http://example.com/activities/1",
+				Type:  vocab.CreateType,
+				Actor: vocab.IRI("http://example.com/actors/jdoe"),
+				Object: &vocab.Object{
+					ID:           "http://example.com/objects/2",
+					Type:         vocab.NoteType,
+					AttributedTo: objects["/actors/jdoe"],
+					InReplyTo: &vocab.Object{
+						ID:           "http://example.com/objects/1",
+						Type:         vocab.NoteType,
+						AttributedTo: objects["/actors/alice"],
+					},
+					Tag: vocab.ItemCollection{vocab.IRI("http://example.com/tags/go"), objects["/actors/alice"]},
+				},
+				To: vocab.ItemCollection{vocab.PublicNS},
+			},
+			wantErr: errors.Join(errf("invalid status received").iri("http://example.com/tags/go").annotate(errors.NotFoundf("/tags/go not found"))),
+		},
+		{
+			name:  "cycles",
+			it:    mockCreate("http://example.com/objects/3"),
+			depth: 5,
+			props: []string{"object", "inReplyTo"},
+			want: &vocab.Activity{
+				ID:     "http://example.com/activities/1",
+				Type:   vocab.CreateType,
+				Actor:  vocab.IRI("http://example.com/actors/jdoe"),
+				Object: objects["/objects/3"],
+				To:     vocab.ItemCollection{vocab.PublicNS},
+			},
+		},
+		{
+			name:  "indirect cycles",
+			it:    mockCreate("http://example.com/objects/4"),
+			depth: 5,
+			props: []string{"object", "inReplyTo"},
+			want: &vocab.Activity{
+				ID:    "http://example.com/activities/1",
+				Type:  vocab.CreateType,
+				Actor: vocab.IRI("http://example.com/actors/jdoe"),
+				Object: &vocab.Object{
+					ID:        "http://example.com/objects/4",
+					Type:      vocab.NoteType,
+					InReplyTo: objects["/objects/5"],
+				},
+				To: vocab.ItemCollection{vocab.PublicNS},
+			},
+		},
+		{
+			name:  "failed loads are left in place",
+			it:    mockCreate("http://example.com/objects/2"),
+			depth: 2,
+			props: []string{"object", "tag"},
+			want: &vocab.Activity{
+				ID:    "http://example.com/activities/1",
+				Type:  vocab.CreateType,
+				Actor: vocab.IRI("http://example.com/actors/jdoe"),
+				Object: &vocab.Object{
+					ID:           "http://example.com/objects/2",
+					Type:         vocab.NoteType,
+					AttributedTo: vocab.IRI("http://example.com/actors/jdoe"),
+					InReplyTo:    vocab.IRI("http://example.com/objects/1"),
+					Tag:          vocab.ItemCollection{vocab.IRI("http://example.com/tags/go"), objects["/actors/alice"]},
+				},
+				To: vocab.ItemCollection{vocab.PublicNS},
+			},
+			wantErr: errors.Join(errf("invalid status received").iri("http://example.com/tags/go").annotate(errors.NotFoundf("/tags/go not found"))),
+		},
+		{
+			name:  "limit",
+			it:    mockCreate("http://example.com/objects/1"),
+			depth: 1,
+			props: []string{"actor", "object"},
+			limit: 1,
+			want: &vocab.Activity{
+				ID:     "http://example.com/activities/1",
+				Type:   vocab.CreateType,
+				Actor:  objects["/actors/jdoe"],
+				Object: vocab.IRI("http://example.com/objects/1"),
+				To:     vocab.ItemCollection{vocab.PublicNS},
+			},
+			wantErr: errors.Join(errors.Newf("reached the limit of %d IRIs to dereference", 1)),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(mockPagesHandler(objects))
+			defer srv.Close()
+
+			c := C{
+				c:            srv.Client(),
+				l:            lw.Dev(lw.SetOutput(t.Output())),
+				hydrateLimit: tt.limit,
+			}
+			err := c.Hydrate(context.Background(), tt.it, tt.depth, tt.props...)
+			if !cmp.Equal(err, tt.wantErr, EquateWeakErrors("")) {
+				t.Errorf("Hydrate() error = %s", cmp.Diff(tt.wantErr, err, EquateWeakErrors("")))
+			}
+			if tt.want == nil {
+				return
+			}
+			wantRaw, _ := vocab.MarshalJSON(tt.want)
+			gotRaw, _ := vocab.MarshalJSON(tt.it)
+			if string(gotRaw) != string(wantRaw) {
+				t.Errorf("Hydrate() got = %s", cmp.Diff(string(wantRaw), string(gotRaw)))
+			}
+		})
+	}
+}
+
+func TestC_Hydrate_concurrent(t *testing.T) {
+	handler := mockPagesHandler(mockHydrateObjects())
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// NOTE(marius): we delay the responses, so the concurrent calls share the loads of the same IRIs
+		time.Sleep(10 * time.Millisecond)
+		handler(w, r)
+	}))
+	defer srv.Close()
+
+	c := New(WithHTTPClient(srv.Client()), WithLogger(lw.Dev(lw.SetOutput(t.Output()))))
+
+	count := 4
+	acts := make([]*vocab.Activity, count)
+	wg := sync.WaitGroup{}
+	for i := range count {
+		acts[i] = mockCreate("http://example.com/objects/2")
+		wg.Go(func() {
+			if err := c.Hydrate(context.Background(), acts[i], 3, "object", "attributedTo", "inReplyTo"); err != nil {
+				t.Errorf("Hydrate() error = %s", err)
+			}
+		})
+	}
+	wg.Wait()
+
+	wantRaw, _ := vocab.MarshalJSON(acts[0])
+	for i, act := range acts[1:] {
+		if gotRaw, _ := vocab.MarshalJSON(act); string(gotRaw) != string(wantRaw) {
+			t.Errorf("Hydrate() %d got = %s", i+1, cmp.Diff(string(wantRaw), string(gotRaw)))
+		}
+	}
+	if vocab.IsIRI(acts[0].Object) {
+		t.Errorf("Hydrate() didn't load the object")
+	}
+}
@@ -0,0 +1,84 @@
+package client
+
+import (
+	"context"
+	"sync"
+
+	vocab "github.com/go-ap/activitypub"
+)
+
+// inflight coalesces concurrent loads of the same IRI, so they share a single request.
+// A nil inflight runs every load separately.
+//
+// NOTE(marius): the loads are keyed only by their IRI, because every client has its own inflight registry,
+// and the authorization functions are set for the whole client, so all the loads sharing a request
+// would have been done with the same credentials anyway.
+type inflight struct {
+	mu    sync.Mutex
+	calls map[vocab.IRI]*inflightCall
+}
+
+type inflightCall struct {
+	done    chan struct{}
+	cancel  context.CancelFunc
+	waiters int
+	it      vocab.Item
+	err     error
+}
+
+func newInflight() *inflight {
+	return &inflight{calls: make(map[vocab.IRI]*inflightCall)}
+}
+
+// do runs loadFn for the iri, unless there's already a load in progress for it, and it waits for the result,
+// or for the ctx to be done.
+// The load doesn't depend on the context of any of the callers, so one of them giving up doesn't fail it for
+// the rest. It gets canceled only when all the callers waiting for it are done.
+func (g *inflight) do(ctx context.Context, iri vocab.IRI, loadFn func(context.Context) (vocab.Item, error)) (vocab.Item, error) {
+	if g == nil {
+		return loadFn(ctx)
+	}
+
+	g.mu.Lock()
+	cl, ok := g.calls[iri]
+	if !ok {
+		loadCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		cl = &inflightCall{done: make(chan struct{}), cancel: cancel}
+		g.calls[iri] = cl
+		go func() {
+			it, err := loadFn(loadCtx)
+			g.forget(iri, cl)
+			cl.it, cl.err = it, err
+			cancel()
+			close(cl.done)
+		}()
+	}
+	cl.waiters++
+	g.mu.Unlock()
+
+	select {
+	case <-cl.done:
+		return cl.it, cl.err
+	case <-ctx.Done():
+		g.mu.Lock()
+		cl.waiters--
+		if cl.waiters == 0 {
+			// NOTE(marius): nobody is waiting for the result anymore
+			if g.calls[iri] == cl {
+				delete(g.calls, iri)
+			}
+			cl.cancel()
+		}
+		g.mu.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+// forget removes the cl call from the ones in progress, if it hasn't been replaced already.
+func (g *inflight) forget(iri vocab.IRI, cl *inflightCall) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.calls[iri] == cl {
+		delete(g.calls, iri)
+	}
+}
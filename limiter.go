@@ -0,0 +1,56 @@
+package client
+
+import (
+	"context"
+	"sync"
+)
+
+// hostLimiter caps the number of concurrent operations for every host.
+// A nil hostLimiter doesn't impose any limits.
+type hostLimiter struct {
+	mu    sync.Mutex
+	max   int
+	slots map[string]chan struct{}
+}
+
+func newHostLimiter(max int) *hostLimiter {
+	if max <= 0 {
+		return nil
+	}
+	return &hostLimiter{max: max, slots: make(map[string]chan struct{})}
+}
+
+// acquire blocks until a slot is available for the host, or the ctx is done.
+func (h *hostLimiter) acquire(ctx context.Context, host string) error {
+	if h == nil {
+		return nil
+	}
+	h.mu.Lock()
+	slots, ok := h.slots[host]
+	if !ok {
+		slots = make(chan struct{}, h.max)
+		h.slots[host] = slots
+	}
+	h.mu.Unlock()
+
+	select {
+	case slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// release frees a slot previously acquired for the host.
+func (h *hostLimiter) release(host string) {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	slots := h.slots[host]
+	h.mu.Unlock()
+
+	if slots != nil {
+		<-slots
+	}
+}
@@ -0,0 +1,38 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func Test_hostLimiter(t *testing.T) {
+	var nilLimiter *hostLimiter
+	if err := nilLimiter.acquire(context.Background(), "example.com"); err != nil {
+		t.Errorf("acquire() on nil limiter error = %s", err)
+	}
+	nilLimiter.release("example.com")
+
+	if got := newHostLimiter(0); got != nil {
+		t.Errorf("newHostLimiter(0) = %v, expected nil", got)
+	}
+
+	h := newHostLimiter(1)
+	if err := h.acquire(context.Background(), "example.com"); err != nil {
+		t.Fatalf("acquire() error = %s", err)
+	}
+	if err := h.acquire(context.Background(), "social.example.com"); err != nil {
+		t.Fatalf("acquire() for different host error = %s", err)
+	}
+
+	ctx, cancelFn := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancelFn()
+	if err := h.acquire(ctx, "example.com"); err == nil {
+		t.Errorf("acquire() over the limit should have failed")
+	}
+
+	h.release("example.com")
+	if err := h.acquire(context.Background(), "example.com"); err != nil {
+		t.Errorf("acquire() after release error = %s", err)
+	}
+}
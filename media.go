@@ -0,0 +1,204 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"path"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	vocab "github.com/go-ap/activitypub"
+	"github.com/go-ap/errors"
+	"github.com/go-ap/jsonld"
+)
+
+// UploadMedia uploads the file to the uploadMedia endpoint of the actor, together with the object, which is the shell
+// of the ActivityPub object that the server creates for the file. If actor is nil, the one set with [WithActor]
+// is used.
+// The file is streamed, and it gets read again only if the request needs to be retried, or if it needs to go through
+// more than one of the authorization functions, for which it must also implement [io.Seeker].
+//
+// It returns the IRI of the created object, from the Location header, and the object, from the response body or
+// loaded from that IRI.
+//
+// https://www.w3.org/TR/activitypub/#uploading-media
+func (c C) UploadMedia(ctx context.Context, actor vocab.Item, file io.Reader, filename string, object vocab.Item) (vocab.IRI, vocab.Item, error) {
+	if file == nil {
+		return "", nil, errf("invalid nil file")
+	}
+	if vocab.IsNil(object) {
+		return "", nil, errf("invalid nil object")
+	}
+	uploadIRI, err := c.uploadMediaEndpoint(ctx, actor)
+	if err != nil {
+		return "", nil, err
+	}
+
+	ob, err := jsonld.WithContext(jsonld.IRI(vocab.ActivityBaseURI)).Marshal(object)
+	if err != nil {
+		return "", nil, errf("unable to marshal object").iri(uploadIRI).annotate(err)
+	}
+	u := newMediaUpload(file, filename, ob)
+
+	req, err := ActivityPubRequest(ctx, string(uploadIRI), u.contentType(), nil)
+	if err != nil {
+		return "", nil, err
+	}
+	req.Body = u.body()
+	req.GetBody = func() (io.ReadCloser, error) {
+		return u.body(), nil
+	}
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return "", nil, err
+	}
+	iri, it, err := c.postResult(uploadIRI, resp)
+	if err != nil || !vocab.IsNil(it) || len(iri) == 0 {
+		return iri, it, err
+	}
+	// NOTE(marius): the server didn't return the object, so we load it
+	it, err = c.loadCtx(ctx, iri)
+	return iri, it, err
+}
+
+func (c C) uploadMediaEndpoint(ctx context.Context, actor vocab.Item) (vocab.IRI, error) {
+	if vocab.IsNil(actor) {
+		actor = c.actor
+	}
+	if vocab.IsNil(actor) {
+		return "", errf("invalid nil actor")
+	}
+	if e := c.Endpoints(); !vocab.IsNil(c.actor) && c.actor.GetLink().Equal(actor.GetLink()) && len(e.UploadMedia) > 0 {
+		return e.UploadMedia, nil
+	}
+	e, err := c.ActorEndpoints(ctx, actor)
+	if err != nil {
+		return "", err
+	}
+	if len(e.UploadMedia) == 0 {
+		return "", errf("actor doesn't have an uploadMedia endpoint").iri(actor.GetLink())
+	}
+	return e.UploadMedia, nil
+}
+
+// mediaUpload writes the multipart/form-data body of an upload, which contains the "object" and "file" parts.
+type mediaUpload struct {
+	// mu makes sure that the bodies of different attempts don't read the file at the same time.
+	mu       sync.Mutex
+	file     io.Reader
+	offset   int64
+	sent     atomic.Bool
+	filename string
+	object   []byte
+	boundary string
+}
+
+func newMediaUpload(file io.Reader, filename string, object []byte) *mediaUpload {
+	u := mediaUpload{
+		file:     file,
+		filename: filename,
+		object:   object,
+		boundary: multipart.NewWriter(io.Discard).Boundary(),
+	}
+	if s, ok := file.(io.Seeker); ok {
+		u.offset, _ = s.Seek(0, io.SeekCurrent)
+	}
+	return &u
+}
+
+func (u *mediaUpload) contentType() string {
+	return "multipart/form-data; boundary=" + u.boundary
+}
+
+// body returns a new request body, which starts streaming the upload when it's first read.
+func (u *mediaUpload) body() io.ReadCloser {
+	return &lazyBody{open: u.stream}
+}
+
+func (u *mediaUpload) stream() io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		u.mu.Lock()
+		defer u.mu.Unlock()
+		_ = pw.CloseWithError(u.write(pw))
+	}()
+	return pr
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+func (u *mediaUpload) write(w io.Writer) error {
+	if s, ok := u.file.(io.Seeker); ok {
+		if _, err := s.Seek(u.offset, io.SeekStart); err != nil {
+			return err
+		}
+	} else if !u.sent.CompareAndSwap(false, true) {
+		return errors.Newf("unable to send %s again, the file can't be rewound", u.filename)
+	}
+
+	mw := multipart.NewWriter(w)
+	if err := mw.SetBoundary(u.boundary); err != nil {
+		return err
+	}
+
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", `form-data; name="object"`)
+	h.Set("Content-Type", ContentTypeJsonActivity)
+	part, err := mw.CreatePart(h)
+	if err != nil {
+		return err
+	}
+	if _, err = part.Write(u.object); err != nil {
+		return err
+	}
+
+	typ := mime.TypeByExtension(path.Ext(u.filename))
+	if len(typ) == 0 {
+		typ = "application/octet-stream"
+	}
+	h = make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename="%s"`, quoteEscaper.Replace(u.filename)))
+	h.Set("Content-Type", typ)
+	if part, err = mw.CreatePart(h); err != nil {
+		return err
+	}
+	if _, err = io.Copy(part, u.file); err != nil {
+		return err
+	}
+	return mw.Close()
+}
+
+// lazyBody is a request body which gets opened when it's first read, so the bodies that never get sent
+// don't hold any resources.
+type lazyBody struct {
+	mu   sync.Mutex
+	open func() io.ReadCloser
+	rc   io.ReadCloser
+}
+
+func (b *lazyBody) Read(p []byte) (int, error) {
+	b.mu.Lock()
+	if b.rc == nil {
+		b.rc = b.open()
+	}
+	rc := b.rc
+	b.mu.Unlock()
+	return rc.Read(p)
+}
+
+func (b *lazyBody) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.rc == nil {
+		b.rc = http.NoBody
+		return nil
+	}
+	return b.rc.Close()
+}
@@ -0,0 +1,151 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"git.sr.ht/~mariusor/lw"
+	vocab "github.com/go-ap/activitypub"
+	"github.com/google/go-cmp/cmp"
+)
+
+func mockMediaHandler(t *testing.T, returnObject bool, uploads *[]string) http.HandlerFunc {
+	created := `{"id":"http://example.com/media/1","type":"Image","name":"kitten","url":"http://example.com/media/1.png"}`
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/~jdoe":
+			w.Header().Set("Content-Type", ContentTypeJsonActivity)
+			_, _ = w.Write([]byte(`{"id":"http://example.com/~jdoe","type":"Person","endpoints":{"uploadMedia":"http://example.com/upload"}}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/~alice":
+			w.Header().Set("Content-Type", ContentTypeJsonActivity)
+			_, _ = w.Write([]byte(`{"id":"http://example.com/~alice","type":"Person"}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/media/1":
+			w.Header().Set("Content-Type", ContentTypeJsonActivity)
+			_, _ = w.Write([]byte(created))
+		case r.Method == http.MethodPost && r.URL.Path == "/upload":
+			if r.Header.Get("Authorization") == "invalid" {
+				_, _ = io.Copy(io.Discard, r.Body)
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			if err := r.ParseMultipartForm(1 << 20); err != nil {
+				t.Errorf("invalid multipart body: %s", err)
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			if ob := r.FormValue("object"); !strings.Contains(ob, `"name":"kitten"`) {
+				t.Errorf("invalid object part %s", ob)
+			}
+			f, fh, err := r.FormFile("file")
+			if err != nil {
+				t.Errorf("missing file part: %s", err)
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			data, _ := io.ReadAll(f)
+			*uploads = append(*uploads, fh.Filename+":"+fh.Header.Get("Content-Type")+":"+string(data))
+
+			w.Header().Set("Location", "http://example.com/media/1")
+			w.WriteHeader(http.StatusCreated)
+			if returnObject {
+				_, _ = w.Write([]byte(created))
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}
+}
+
+func TestC_UploadMedia(t *testing.T) {
+	want := &vocab.Object{
+		ID:   "http://example.com/media/1",
+		Type: vocab.ImageType,
+		Name: vocab.DefaultNaturalLanguage("kitten"),
+		URL:  vocab.IRI("http://example.com/media/1.png"),
+	}
+	tests := []struct {
+		name         string
+		actor        vocab.Item
+		file         io.Reader
+		authFns      []func(*http.Request) error
+		returnObject bool
+		wantIRI      vocab.IRI
+		want         vocab.Item
+		wantErr      bool
+		wantUploads  []string
+	}{
+		{
+			name:         "created object in the response",
+			actor:        vocab.IRI("http://example.com/~jdoe"),
+			file:         strings.NewReader("PNG data"),
+			returnObject: true,
+			wantIRI:      "http://example.com/media/1",
+			want:         want,
+			wantUploads:  []string{"kitten.png:image/png:PNG data"},
+		},
+		{
+			name:        "created object is loaded",
+			actor:       vocab.IRI("http://example.com/~jdoe"),
+			file:        io.MultiReader(strings.NewReader("PNG data")),
+			wantIRI:     "http://example.com/media/1",
+			want:        want,
+			wantUploads: []string{"kitten.png:image/png:PNG data"},
+		},
+		{
+			name:         "file is sent again to the next authorization function",
+			actor:        vocab.IRI("http://example.com/~jdoe"),
+			file:         strings.NewReader("PNG data"),
+			authFns:      []func(*http.Request) error{mockAuthFn("invalid"), mockAuthFn("valid")},
+			returnObject: true,
+			wantIRI:      "http://example.com/media/1",
+			want:         want,
+			wantUploads:  []string{"kitten.png:image/png:PNG data"},
+		},
+		{
+			name:    "file can't be sent again",
+			actor:   vocab.IRI("http://example.com/~jdoe"),
+			file:    io.MultiReader(strings.NewReader("PNG data")),
+			authFns: []func(*http.Request) error{mockAuthFn("invalid"), mockAuthFn("valid")},
+			wantErr: true,
+		},
+		{
+			name:    "actor without uploadMedia endpoint",
+			actor:   vocab.IRI("http://example.com/~alice"),
+			file:    strings.NewReader("PNG data"),
+			wantErr: true,
+		},
+		{
+			name:    "nil actor",
+			file:    strings.NewReader("PNG data"),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var uploads []string
+			srv := httptest.NewServer(mockMediaHandler(t, tt.returnObject, &uploads))
+			defer srv.Close()
+
+			c := C{c: srv.Client(), l: lw.Dev(lw.SetOutput(t.Output())), authFns: tt.authFns, inflight: newInflight()}
+			object := &vocab.Object{Type: vocab.ImageType, Name: vocab.DefaultNaturalLanguage("kitten")}
+
+			iri, got, err := c.UploadMedia(context.Background(), tt.actor, tt.file, "kitten.png", object)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("UploadMedia() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if iri != tt.wantIRI {
+				t.Errorf("UploadMedia() IRI = %s, want %s", iri, tt.wantIRI)
+			}
+			if !cmp.Equal(got, tt.want, EquateItems) {
+				t.Errorf("UploadMedia() = %s", cmp.Diff(tt.want, got, EquateItems))
+			}
+			if !cmp.Equal(uploads, tt.wantUploads) {
+				t.Errorf("UploadMedia() uploads = %s", cmp.Diff(tt.wantUploads, uploads))
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"sync"
+	"time"
+
+	vocab "github.com/go-ap/activitypub"
+	"github.com/go-ap/errors"
+)
+
+// NodeInfo schemas that we know how to read, in the order of preference.
+var nodeInfoSchemas = []string{
+	"http://nodeinfo.diaspora.software/ns/schema/2.1",
+	"http://nodeinfo.diaspora.software/ns/schema/2.0",
+}
+
+// nodeInfoTTL is the duration for which we keep the NodeInfo document of a host.
+const nodeInfoTTL = 24 * time.Hour
+
+// NodeInfo is the metadata document that a server publishes about the software it runs.
+//
+// https://github.com/jhass/nodeinfo/blob/main/PROTOCOL.md
+type NodeInfo struct {
+	Version           string           `json:"version"`
+	Software          NodeInfoSoftware `json:"software"`
+	Protocols         []string         `json:"protocols"`
+	Services          NodeInfoServices `json:"services"`
+	OpenRegistrations bool             `json:"openRegistrations"`
+	Usage             NodeInfoUsage    `json:"usage"`
+	Metadata          map[string]any   `json:"metadata,omitempty"`
+}
+
+// NodeInfoSoftware describes the software that a server runs.
+type NodeInfoSoftware struct {
+	Name       string `json:"name"`
+	Version    string `json:"version"`
+	Repository string `json:"repository,omitempty"`
+	Homepage   string `json:"homepage,omitempty"`
+}
+
+// NodeInfoServices lists the third party services that a server can interact with.
+type NodeInfoServices struct {
+	Inbound  []string `json:"inbound"`
+	Outbound []string `json:"outbound"`
+}
+
+// NodeInfoUsage contains the usage statistics of a server.
+type NodeInfoUsage struct {
+	Users         NodeInfoUsers `json:"users"`
+	LocalPosts    int           `json:"localPosts,omitempty"`
+	LocalComments int           `json:"localComments,omitempty"`
+}
+
+// NodeInfoUsers contains the user statistics of a server.
+type NodeInfoUsers struct {
+	Total          int `json:"total,omitempty"`
+	ActiveHalfyear int `json:"activeHalfyear,omitempty"`
+	ActiveMonth    int `json:"activeMonth,omitempty"`
+}
+
+// NodeInfo discovers and loads the NodeInfo document of the host.
+// The documents are kept for a day, so subsequent calls for the same host don't need any requests.
+//
+// https://github.com/jhass/nodeinfo/blob/main/PROTOCOL.md
+func (c C) NodeInfo(ctx context.Context, host string) (*NodeInfo, error) {
+	if len(host) == 0 {
+		return nil, errors.Newf("invalid empty host")
+	}
+	if ni := c.nodeInfo.get(host); ni != nil {
+		return ni, nil
+	}
+
+	u := url.URL{Scheme: "https", Host: host, Path: "/.well-known/nodeinfo"}
+	links, err := c.jrd(ctx, u.String())
+	if err != nil {
+		return nil, errors.Annotatef(err, "unable to discover NodeInfo for %s", host)
+	}
+
+	href := ""
+	for _, schema := range nodeInfoSchemas {
+		for _, l := range links.Links {
+			if l.Rel == schema && len(l.Href) > 0 {
+				href = l.Href
+				break
+			}
+		}
+		if len(href) > 0 {
+			break
+		}
+	}
+	if len(href) == 0 {
+		return nil, errors.NotFoundf("no supported NodeInfo schema found for %s", host)
+	}
+
+	body, _, err := c.fetchDocument(ctx, href, ContentTypeJson)
+	if err != nil {
+		return nil, errors.Annotatef(err, "unable to load NodeInfo for %s", host)
+	}
+	ni := NodeInfo{}
+	if err = json.Unmarshal(body, &ni); err != nil {
+		return nil, errf("invalid NodeInfo document").iri(vocab.IRI(href)).annotate(err)
+	}
+	c.nodeInfo.set(host, &ni)
+	return &ni, nil
+}
+
+// nodeInfoCache keeps the NodeInfo documents per host.
+// A nil nodeInfoCache doesn't keep anything.
+type nodeInfoCache struct {
+	mu sync.RWMutex
+	m  map[string]nodeInfoEntry
+}
+
+type nodeInfoEntry struct {
+	ni      *NodeInfo
+	expires time.Time
+}
+
+func newNodeInfoCache() *nodeInfoCache {
+	return &nodeInfoCache{m: make(map[string]nodeInfoEntry)}
+}
+
+func (n *nodeInfoCache) get(host string) *NodeInfo {
+	if n == nil {
+		return nil
+	}
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	e, ok := n.m[host]
+	if !ok || TimeNow().After(e.expires) {
+		return nil
+	}
+	return e.ni
+}
+
+func (n *nodeInfoCache) set(host string, ni *NodeInfo) {
+	if n == nil {
+		return
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.m[host] = nodeInfoEntry{ni: ni, expires: TimeNow().Add(nodeInfoTTL)}
+}
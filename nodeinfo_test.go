@@ -0,0 +1,106 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"git.sr.ht/~mariusor/lw"
+	"github.com/go-ap/errors"
+	"github.com/google/go-cmp/cmp"
+)
+
+func mockNodeInfoHandler(docs map[string]string, requests *atomic.Int32) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		doc, ok := docs[r.URL.Path]
+		if !ok {
+			errors.NotFound.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(doc))
+	}
+}
+
+func TestC_NodeInfo(t *testing.T) {
+	tests := []struct {
+		name         string
+		docs         map[string]string
+		want         *NodeInfo
+		wantErr      error
+		wantRequests int32
+	}{
+		{
+			name:         "not found",
+			wantErr:      errors.Annotatef(errf("invalid status received").iri("https://example.com/.well-known/nodeinfo").annotate(errors.NotFoundf("/.well-known/nodeinfo not found")), "unable to discover NodeInfo for example.com"),
+			wantRequests: 2,
+		},
+		{
+			name: "unsupported schema",
+			docs: map[string]string{
+				"/.well-known/nodeinfo": `{"links":[{"rel":"http://nodeinfo.diaspora.software/ns/schema/1.0","href":"https://example.com/nodeinfo/1.0"}]}`,
+			},
+			wantErr:      errors.NotFoundf("no supported NodeInfo schema found for example.com"),
+			wantRequests: 2,
+		},
+		{
+			name: "prefers the newest schema",
+			docs: map[string]string{
+				"/.well-known/nodeinfo": `{"links":[
+					{"rel":"http://nodeinfo.diaspora.software/ns/schema/2.0","href":"https://example.com/nodeinfo/2.0"},
+					{"rel":"http://nodeinfo.diaspora.software/ns/schema/2.1","href":"https://example.com/nodeinfo/2.1"}
+				]}`,
+				"/nodeinfo/2.0": `{"version":"2.0","software":{"name":"old"}}`,
+				"/nodeinfo/2.1": `{
+					"version":"2.1",
+					"software":{"name":"mastodon","version":"4.3.0","repository":"https://github.com/mastodon/mastodon"},
+					"protocols":["activitypub"],
+					"services":{"inbound":[],"outbound":[]},
+					"openRegistrations":true,
+					"usage":{"users":{"total":10,"activeMonth":2,"activeHalfyear":5},"localPosts":100},
+					"metadata":{"nodeName":"Example"}
+				}`,
+			},
+			want: &NodeInfo{
+				Version:           "2.1",
+				Software:          NodeInfoSoftware{Name: "mastodon", Version: "4.3.0", Repository: "https://github.com/mastodon/mastodon"},
+				Protocols:         []string{"activitypub"},
+				Services:          NodeInfoServices{Inbound: []string{}, Outbound: []string{}},
+				OpenRegistrations: true,
+				Usage:             NodeInfoUsage{Users: NodeInfoUsers{Total: 10, ActiveHalfyear: 5, ActiveMonth: 2}, LocalPosts: 100},
+				Metadata:          map[string]any{"nodeName": "Example"},
+			},
+			wantRequests: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := atomic.Int32{}
+			srv := httptest.NewTLSServer(mockNodeInfoHandler(tt.docs, &requests))
+			defer srv.Close()
+
+			c := C{
+				c:        srv.Client(),
+				l:        lw.Dev(lw.SetOutput(t.Output())),
+				nodeInfo: newNodeInfoCache(),
+			}
+			// NOTE(marius): the second call should use the cached document, when there is one
+			for range 2 {
+				got, err := c.NodeInfo(context.Background(), "example.com")
+				if !cmp.Equal(err, tt.wantErr, EquateWeakErrors("")) {
+					t.Errorf("NodeInfo() error = %s", cmp.Diff(tt.wantErr, err, EquateWeakErrors("")))
+				}
+				if !cmp.Equal(got, tt.want) {
+					t.Errorf("NodeInfo() got = %s", cmp.Diff(tt.want, got))
+				}
+			}
+			if got := requests.Load(); got != tt.wantRequests {
+				t.Errorf("NodeInfo() requests = %d, want %d", got, tt.wantRequests)
+			}
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"git.sr.ht/~mariusor/lw"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestProfiles_Host(t *testing.T) {
+	p := NewProfiles()
+	p.SetHostSoftware("mastodon.example.com", "Mastodon")
+	p.SetHostSoftware("tags.pub", "mastodon")
+
+	tests := []struct {
+		name     string
+		profiles *Profiles
+		host     string
+		want     HostProfile
+	}{
+		{
+			name: "nil registry",
+			host: "example.com",
+			want: DefaultHostProfile,
+		},
+		{
+			name:     "unknown host",
+			profiles: p,
+			host:     "example.com",
+			want:     DefaultHostProfile,
+		},
+		{
+			name:     "host profile",
+			profiles: p,
+			host:     "TAGS.pub",
+			want:     p.hosts["tags.pub"],
+		},
+		{
+			name:     "software profile",
+			profiles: p,
+			host:     "mastodon.example.com",
+			want:     p.software["mastodon"],
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.profiles.Host(tt.host); !cmp.Equal(got, tt.want) {
+				t.Errorf("Host() = %s", cmp.Diff(tt.want, got))
+			}
+		})
+	}
+}
+
+func mockAuthFn(val string) func(*http.Request) error {
+	return func(r *http.Request) error {
+		r.Header.Set("Authorization", val)
+		return nil
+	}
+}
+
+func TestC_Do_withProfiles(t *testing.T) {
+	tests := []struct {
+		name      string
+		profile   *HostProfile
+		opts      []OptionFn
+		req       *http.Request
+		statuses  map[string]int
+		wantAuth  []string
+		wantState int
+	}{
+		{
+			name:      "default profile retries on 401",
+			opts:      []OptionFn{WithAuthorizationFn(mockAuthFn("first"), mockAuthFn("second"))},
+			req:       mockGetReq(),
+			statuses:  map[string]int{"first": http.StatusUnauthorized},
+			wantAuth:  []string{"first", "second"},
+			wantState: http.StatusOK,
+		},
+		{
+			name:      "default profile doesn't retry on 400",
+			opts:      []OptionFn{WithAuthorizationFn(mockAuthFn("first"), mockAuthFn("second"))},
+			req:       mockGetReq(),
+			statuses:  map[string]int{"first": http.StatusBadRequest},
+			wantAuth:  []string{"first"},
+			wantState: http.StatusBadRequest,
+		},
+		{
+			name:      "profile retries on 400",
+			profile:   &HostProfile{RetryStatuses: []int{http.StatusBadRequest}, AuthorizedFetch: true},
+			opts:      []OptionFn{WithAuthorizationFn(mockAuthFn("first"), mockAuthFn("second"))},
+			req:       mockGetReq(),
+			statuses:  map[string]int{"first": http.StatusBadRequest},
+			wantAuth:  []string{"first", "second"},
+			wantState: http.StatusOK,
+		},
+		{
+			name:    "preferred scheme is tried first",
+			profile: &HostProfile{RetryStatuses: []int{http.StatusUnauthorized}, PreferredAuth: AuthRFC9421Signature, AuthorizedFetch: true},
+			opts: []OptionFn{
+				WithAuthorizationFn(mockAuthFn("unknown")),
+				WithAuthorization(AuthDraftSignature, mockAuthFn("draft")),
+				WithAuthorization(AuthRFC9421Signature, mockAuthFn("rfc9421")),
+			},
+			req:       mockGetReq(),
+			statuses:  map[string]int{"rfc9421": http.StatusUnauthorized},
+			wantAuth:  []string{"rfc9421", "unknown"},
+			wantState: http.StatusOK,
+		},
+		{
+			name:      "no authorized fetch",
+			profile:   &HostProfile{RetryStatuses: []int{http.StatusUnauthorized}},
+			opts:      []OptionFn{WithAuthorizationFn(mockAuthFn("first"))},
+			req:       mockGetReq(),
+			statuses:  map[string]int{"": http.StatusUnauthorized},
+			wantAuth:  []string{"", "first"},
+			wantState: http.StatusOK,
+		},
+		{
+			name:      "no authorized fetch doesn't apply to POST",
+			profile:   &HostProfile{RetryStatuses: []int{http.StatusUnauthorized}},
+			opts:      []OptionFn{WithAuthorizationFn(mockAuthFn("first"))},
+			req:       mockPostReq([]byte("{}")),
+			wantAuth:  []string{"first"},
+			wantState: http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotAuth []string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				auth := r.Header.Get("Authorization")
+				gotAuth = append(gotAuth, auth)
+				if st, ok := tt.statuses[auth]; ok {
+					w.WriteHeader(st)
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer srv.Close()
+
+			c := C{c: srv.Client(), l: lw.Dev(lw.SetOutput(t.Output()))}
+			if tt.profile != nil {
+				c.profiles = NewProfiles()
+				c.profiles.SetHost("example.com", *tt.profile)
+			}
+			for _, fn := range tt.opts {
+				fn(&c)
+			}
+
+			res, err := c.Do(tt.req)
+			if err != nil {
+				t.Fatalf("Do() error = %s", err)
+			}
+			if res.StatusCode != tt.wantState {
+				t.Errorf("Do() status = %d, want %d", res.StatusCode, tt.wantState)
+			}
+			if !cmp.Equal(gotAuth, tt.wantAuth) {
+				t.Errorf("Do() authorizations = %s", cmp.Diff(tt.wantAuth, gotAuth))
+			}
+		})
+	}
+}
+
+func TestC_DiscoverProfile(t *testing.T) {
+	docs := map[string]string{
+		"/.well-known/nodeinfo": `{"links":[{"rel":"http://nodeinfo.diaspora.software/ns/schema/2.0","href":"https://example.com/nodeinfo/2.0"}]}`,
+		"/nodeinfo/2.0":         `{"version":"2.0","software":{"name":"gotosocial","version":"0.17.0"}}`,
+	}
+	requests := atomic.Int32{}
+	srv := httptest.NewTLSServer(mockNodeInfoHandler(docs, &requests))
+	defer srv.Close()
+
+	c := C{
+		c:        srv.Client(),
+		l:        lw.Dev(lw.SetOutput(t.Output())),
+		profiles: NewProfiles(),
+	}
+	got, err := c.DiscoverProfile(context.Background(), "example.com")
+	if err != nil {
+		t.Fatalf("DiscoverProfile() error = %s", err)
+	}
+	want := c.profiles.software["gotosocial"]
+	if !cmp.Equal(got, want) {
+		t.Errorf("DiscoverProfile() = %s", cmp.Diff(want, got))
+	}
+	if hp := c.profiles.Host("example.com"); !cmp.Equal(hp, want) {
+		t.Errorf("Host() after discovery = %s", cmp.Diff(want, hp))
+	}
+}
@@ -7,6 +7,8 @@ import (
 	"net/url"
 	"slices"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/go-ap/errors"
 )
@@ -19,32 +21,99 @@ var shouldProxyStatuses = []int{http.StatusForbidden, http.StatusUnauthorized, h
 // provided by the server owning the proxy URL.
 // If the server requires authorization, that should be handled by the Base transport - using most likely the OAuth2
 // round tripper.
+// The hosts for which the proxy was needed are remembered for a while, so the following requests to them,
+// like the ones for the pages of a collection, skip the direct attempt.
 func (c C) tryProxiedRequest(req *http.Request) (*http.Response, error) {
 	if req == nil {
 		return nil, errors.Newf("nil request")
 	}
-	proxyURL, err := c.proxyURL.URL()
-	if err != nil {
-		return nil, err
+	proxyURL := c.proxyEndpoint(req)
+	if proxyURL == nil {
+		return c.Do(req)
 	}
 
-	needsProxying := req.Method == http.MethodGet
-	// NOTE(marius): if the request is done to the same host as the proxyUrl we most likely don't need to use the proxy
-	if proxyURL == nil || strings.EqualFold(proxyURL.Host, req.URL.Host) {
-		needsProxying = false
+	host := strings.ToLower(req.URL.Host)
+	if !c.proxied.has(host) {
+		res, err := c.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		// NOTE(marius): if the first attempt failed, and we fulfill the proxying requirements, try again
+		if !slices.Contains(shouldProxyStatuses, res.StatusCode) {
+			return res, err
+		}
+		_, _ = io.Copy(io.Discard, res.Body)
+		_ = res.Body.Close()
 	}
 
-	res, err := c.Do(req)
+	res, err := c.Do(buildProxyRequest(req, proxyURL))
 	if err != nil {
 		return nil, err
 	}
-	// NOTE(marius): if the first attempt failed, and we fulfill the proxying requirements, try again
-	if !needsProxying || !slices.Contains(shouldProxyStatuses, res.StatusCode) {
-		return res, err
+	// NOTE(marius): if the proxy can't load the IRI either, or it fails itself, there's no point in using it
+	//  for the host again
+	failed := slices.Contains(shouldProxyStatuses, res.StatusCode) || res.StatusCode >= http.StatusInternalServerError
+	c.proxied.set(host, !failed)
+	return res, nil
+}
+
+// proxyEndpoint returns the URL of the proxyUrl endpoint, if the req should go through it when it fails.
+func (c C) proxyEndpoint(req *http.Request) *url.URL {
+	iri := c.proxyEndpointIRI()
+	if req.Method != http.MethodGet || len(iri) == 0 {
+		return nil
 	}
+	proxyURL, err := iri.URL()
+	if err != nil {
+		return nil
+	}
+	// NOTE(marius): if the request is done to the same host as the proxyUrl we most likely don't need to use the proxy
+	if strings.EqualFold(proxyURL.Host, req.URL.Host) {
+		return nil
+	}
+	return proxyURL
+}
+
+// defaultProxiedTTL is the duration for which the requests to a host go directly through the proxyUrl endpoint,
+// after a direct request to it failed.
+const defaultProxiedTTL = time.Hour
 
-	req1 := buildProxyRequest(req, proxyURL)
-	return c.Do(req1)
+// proxiedHosts keeps the hosts for which the requests need to go through the proxyUrl endpoint,
+// with the time when that has been found out. A nil proxiedHosts doesn't remember any.
+type proxiedHosts struct {
+	mu    sync.RWMutex
+	ttl   time.Duration
+	hosts map[string]time.Time
+}
+
+func newProxiedHosts() *proxiedHosts {
+	return &proxiedHosts{ttl: defaultProxiedTTL, hosts: make(map[string]time.Time)}
+}
+
+func (p *proxiedHosts) has(host string) bool {
+	if p == nil {
+		return false
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	since, ok := p.hosts[host]
+	return ok && TimeNow().Sub(since) <= p.ttl
+}
+
+func (p *proxiedHosts) set(host string, proxied bool) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !proxied {
+		delete(p.hosts, host)
+		return
+	}
+	// NOTE(marius): we keep the time of the first failure, so the host gets tried directly again after the ttl
+	if since, ok := p.hosts[host]; !ok || TimeNow().Sub(since) > p.ttl {
+		p.hosts[host] = TimeNow()
+	}
 }
 
 func setProxyFetchID(req *http.Request, id string) error {
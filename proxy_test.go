@@ -2,12 +2,14 @@ package client
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"testing"
+	"time"
 
 	vocab "github.com/go-ap/activitypub"
 	"github.com/google/go-cmp/cmp"
@@ -249,3 +251,103 @@ func mockProxyReq(req *http.Request, proxy string) *http.Request {
 	r.RemoteAddr = ""
 	return r
 }
+
+func TestC_Items_throughProxy(t *testing.T) {
+	docs := map[string]string{
+		"http://example.com/outbox":        `{"id":"http://example.com/outbox","type":"OrderedCollection","first":"http://example.com/outbox?page=1"}`,
+		"http://example.com/outbox?page=1": `{"id":"http://example.com/outbox?page=1","type":"OrderedCollectionPage","next":"http://example.com/outbox?page=2","orderedItems":["http://example.com/1"]}`,
+		"http://example.com/outbox?page=2": `{"id":"http://example.com/outbox?page=2","type":"OrderedCollectionPage","orderedItems":["http://example.com/2"]}`,
+	}
+	direct, proxied := 0, 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/proxy" {
+			direct++
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		proxied++
+		_ = r.ParseForm()
+		doc, ok := docs[r.PostForm.Get("id")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", ContentTypeJsonActivity)
+		_, _ = w.Write([]byte(doc))
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name        string
+		proxyURL    vocab.IRI
+		want        vocab.ItemCollection
+		wantErr     bool
+		wantDirect  int
+		wantProxied int
+	}{
+		{
+			name:       "without proxyUrl",
+			wantErr:    true,
+			wantDirect: 1,
+		},
+		{
+			name:        "with proxyUrl",
+			proxyURL:    vocab.IRI(srv.URL + "/proxy"),
+			want:        vocab.ItemCollection{vocab.IRI("http://example.com/1"), vocab.IRI("http://example.com/2")},
+			wantDirect:  1,
+			wantProxied: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			direct, proxied = 0, 0
+			c := New(WithHTTPClient(srv.Client()), WithProxyURL(tt.proxyURL))
+
+			var got vocab.ItemCollection
+			var err error
+			for it, e := range c.Items(context.Background(), "http://example.com/outbox") {
+				if e != nil {
+					err = e
+					break
+				}
+				got = append(got, it)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Items() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if !cmp.Equal(got, tt.want, EquateItems) {
+				t.Errorf("Items() = %s", cmp.Diff(tt.want, got, EquateItems))
+			}
+			if direct != tt.wantDirect || proxied != tt.wantProxied {
+				t.Errorf("Items() requests direct = %d, proxied = %d, want %d, %d", direct, proxied, tt.wantDirect, tt.wantProxied)
+			}
+		})
+	}
+}
+
+func Test_proxiedHosts(t *testing.T) {
+	defer func(fn func() time.Time) {
+		TimeNow = fn
+	}(TimeNow)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	TimeNow = func() time.Time { return now }
+
+	p := newProxiedHosts()
+	p.set("example.com", true)
+	if !p.has("example.com") {
+		t.Errorf("has() = false for a proxied host")
+	}
+
+	now = now.Add(defaultProxiedTTL / 2)
+	p.set("example.com", true)
+	now = now.Add(defaultProxiedTTL/2 + time.Second)
+	if p.has("example.com") {
+		t.Errorf("has() = true for a host proxied for longer than the ttl")
+	}
+
+	p.set("example.com", true)
+	p.set("example.com", false)
+	if p.has("example.com") {
+		t.Errorf("has() = true for a host for which the proxy failed")
+	}
+}
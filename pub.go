@@ -2,7 +2,10 @@ package client
 
 import (
 	"context"
+	"iter"
 	"slices"
+	"strconv"
+	"strings"
 
 	vocab "github.com/go-ap/activitypub"
 	"github.com/go-ap/errors"
@@ -39,7 +42,7 @@ func (c C) Inbox(ctx context.Context, actor vocab.Item, ff ...filters.Check) (vo
 	if err := validateActor(actor); err != nil {
 		return nil, err
 	}
-	return c.collection(ctx, inbox(actor, ff...))
+	return c.collection(ctx, inbox(actor, ff...), ff...)
 }
 
 // Outbox fetches the outbox collection of the actor Item. It applies filters to the received collection object.
@@ -47,7 +50,7 @@ func (c C) Outbox(ctx context.Context, actor vocab.Item, ff ...filters.Check) (v
 	if err := validateActor(actor); err != nil {
 		return nil, err
 	}
-	return c.collection(ctx, outbox(actor, ff...))
+	return c.collection(ctx, outbox(actor, ff...), ff...)
 }
 
 // Following fetches the following collection of the actor Item. It applies filters to the received collection object.
@@ -55,7 +58,7 @@ func (c C) Following(ctx context.Context, actor vocab.Item, ff ...filters.Check)
 	if err := validateActor(actor); err != nil {
 		return nil, err
 	}
-	return c.collection(ctx, following(actor, ff...))
+	return c.collection(ctx, following(actor, ff...), ff...)
 }
 
 // Followers fetches the followers collection of the actor Item. It applies filters to the received collection object.
@@ -63,7 +66,7 @@ func (c C) Followers(ctx context.Context, actor vocab.Item, ff ...filters.Check)
 	if err := validateActor(actor); err != nil {
 		return nil, err
 	}
-	return c.collection(ctx, followers(actor, ff...))
+	return c.collection(ctx, followers(actor, ff...), ff...)
 }
 
 // Likes fetches the likes collection of the object Item. It applies filters to the received collection object.
@@ -71,7 +74,7 @@ func (c C) Likes(ctx context.Context, object vocab.Item, ff ...filters.Check) (v
 	if err := validateObject(object); err != nil {
 		return nil, err
 	}
-	return c.collection(ctx, likes(object, ff...))
+	return c.collection(ctx, likes(object, ff...), ff...)
 }
 
 // Liked fetches the liked collection of the actor Item. It applies filters to the received collection object.
@@ -79,7 +82,7 @@ func (c C) Liked(ctx context.Context, actor vocab.Item, ff ...filters.Check) (vo
 	if err := validateActor(actor); err != nil {
 		return nil, err
 	}
-	return c.collection(ctx, liked(actor, ff...))
+	return c.collection(ctx, liked(actor, ff...), ff...)
 }
 
 // Replies fetches the replies collection of the object Item. It applies filters to the received collection object.
@@ -87,7 +90,7 @@ func (c C) Replies(ctx context.Context, object vocab.Item, ff ...filters.Check)
 	if err := validateObject(object); err != nil {
 		return nil, err
 	}
-	return c.collection(ctx, replies(object, ff...))
+	return c.collection(ctx, replies(object, ff...), ff...)
 }
 
 // Shares fetches the shares collection of the object Item. It applies filters to the received collection object.
@@ -95,12 +98,12 @@ func (c C) Shares(ctx context.Context, object vocab.Item, ff ...filters.Check) (
 	if err := validateObject(object); err != nil {
 		return nil, err
 	}
-	return c.collection(ctx, shares(object, ff...))
+	return c.collection(ctx, shares(object, ff...), ff...)
 }
 
 // Collection fetches the iri [vocab.IRI] as a collection. It applies filters to the received object.
 func (c C) Collection(ctx context.Context, iri vocab.IRI, ff ...filters.Check) (vocab.CollectionInterface, error) {
-	return c.collection(ctx, irif(iri, ff...))
+	return c.collection(ctx, irif(iri, ff...), ff...)
 }
 
 // Actor dereferences the iri [vocab.IRI] as an actor object.
@@ -214,7 +217,7 @@ func FilterQueryString(f ...filters.Check) string {
 	return "?" + filters.ToValues(f...).Encode()
 }
 
-func (c C) collection(ctx context.Context, i vocab.IRI) (vocab.CollectionInterface, error) {
+func (c C) collection(ctx context.Context, i vocab.IRI, ff ...filters.Check) (vocab.CollectionInterface, error) {
 	it, err := c.CtxLoadIRI(ctx, i)
 	if err != nil {
 		return nil, errors.Annotatef(err, "unable to load")
@@ -222,7 +225,75 @@ func (c C) collection(ctx context.Context, i vocab.IRI) (vocab.CollectionInterfa
 	if vocab.IsNil(it) {
 		return nil, errors.Newf("unable to load IRI, nil item: %s", i)
 	}
+	col, err := toCollectionInterface(it)
+	if err != nil {
+		return nil, err
+	}
+	return c.filter(ctx, i, col, ff...), nil
+}
+
+// filter applies the ff [filters.Check] list on the items of the col collection, if the client
+// has been configured to do local filtering, and it reports where the filtering has been done to the function
+// set with [ReportFiltering] on the ctx. Items which are only IRIs can't be checked, so they are kept.
+func (c C) filter(ctx context.Context, i vocab.IRI, col vocab.CollectionInterface, ff ...filters.Check) vocab.CollectionInterface {
+	if !c.localFilters || len(ff) == 0 {
+		return col
+	}
+
+	by := FilteredByServer
+	// NOTE(marius): the loaded collection is shared with the concurrent loads of the same IRI,
+	//  so we filter its items into a new slice, set on a copy of it.
+	keep := func(items vocab.ItemCollection) vocab.ItemCollection {
+		kept := make(vocab.ItemCollection, 0, len(items))
+		for _, it := range items {
+			if vocab.IsIRI(it) || matchesAll(it, ff...) {
+				kept = append(kept, it)
+			}
+		}
+		if len(kept) < len(items) {
+			by = FilteredByClient
+		}
+		return kept
+	}
+	switch cc := col.(type) {
+	case *vocab.OrderedCollection:
+		cp := *cc
+		cp.OrderedItems = keep(cc.OrderedItems)
+		col = &cp
+	case *vocab.OrderedCollectionPage:
+		cp := *cc
+		cp.OrderedItems = keep(cc.OrderedItems)
+		col = &cp
+	case *vocab.Collection:
+		cp := *cc
+		cp.Items = keep(cc.Items)
+		col = &cp
+	case *vocab.CollectionPage:
+		cp := *cc
+		cp.Items = keep(cc.Items)
+		col = &cp
+	case *vocab.ItemCollection:
+		cp := keep(*cc)
+		col = &cp
+	}
+	if fn, ok := ctx.Value(filterReportKey{}).(func(vocab.IRI, FilteredBy)); ok && fn != nil {
+		fn(i, by)
+	}
+	return col
+}
+
+func matchesAll(it vocab.Item, ff ...filters.Check) bool {
+	for _, f := range ff {
+		if !f.Match(it) {
+			return false
+		}
+	}
+	return true
+}
+
+func toCollectionInterface(it vocab.Item) (vocab.CollectionInterface, error) {
 	var col vocab.CollectionInterface
+	var err error
 
 	typ := it.GetType()
 	if !vocab.CollectionTypes.Match(it.GetType()) {
@@ -251,6 +322,379 @@ func (c C) collection(ctx context.Context, i vocab.IRI) (vocab.CollectionInterfa
 	return col, nil
 }
 
+// Direction designates the order in which the pages of a collection are walked.
+type Direction int8
+
+const (
+	// Forward walks a collection starting from its "first" page and following the "next" links.
+	Forward Direction = iota
+	// Backward walks a collection starting from its "last" page and following the "prev" links.
+	// The items of every page are returned in reverse order, so for collections which are sorted
+	// newest first, like inboxes and outboxes, this returns the oldest items first.
+	Backward
+)
+
+// Page is one of the documents loaded while walking a collection.
+type Page struct {
+	// IRI identifies the page. It can be stored and passed later to [C.Pages] to resume the walk from it.
+	IRI vocab.IRI
+	// Next is the IRI of the page that follows this one in the walking Direction.
+	// It is empty when this is the last page.
+	Next vocab.IRI
+	// Items are the items of the page, in the walking Direction.
+	Items vocab.ItemCollection
+}
+
+// Items returns an iterator over the items of the collection found at the iri [vocab.IRI].
+// It applies filters to the collection IRI, and then it follows the "first" and "next" properties
+// of the collection and of its pages, until there are no more pages, the context is canceled,
+// or one of the pages points back to an already visited one.
+func (c C) Items(ctx context.Context, iri vocab.IRI, ff ...filters.Check) iter.Seq2[vocab.Item, error] {
+	return c.items(ctx, irif(iri, ff...), ff...)
+}
+
+// Pages returns an iterator over the pages of the collection found at the iri [vocab.IRI], walked in
+// the dir [Direction]. It applies filters to the collection IRI.
+// The iri can designate the collection itself, or one of its pages, when resuming a previous walk.
+func (c C) Pages(ctx context.Context, iri vocab.IRI, dir Direction, ff ...filters.Check) iter.Seq2[Page, error] {
+	return c.pages(ctx, irif(iri, ff...), dir, ff...)
+}
+
+// items walks the collection pages forward, starting at the start collection, or page, which can be
+// an IRI, or an already loaded object.
+func (c C) items(ctx context.Context, start vocab.Item, ff ...filters.Check) iter.Seq2[vocab.Item, error] {
+	return func(yield func(vocab.Item, error) bool) {
+		for p, err := range c.pages(ctx, start, Forward, ff...) {
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			for _, it := range p.Items {
+				if !yield(it, nil) {
+					return
+				}
+			}
+		}
+	}
+}
+
+func (c C) pages(ctx context.Context, start vocab.Item, dir Direction, ff ...filters.Check) iter.Seq2[Page, error] {
+	return func(yield func(Page, error) bool) {
+		visited := make(map[vocab.IRI]struct{})
+		// NOTE(marius): some servers embed the items of the first page in the collection itself,
+		// so we keep them around to avoid returning them twice.
+		var seen vocab.ItemCollection
+
+		pf := c.prefetcher(ctx, ff...)
+		defer pf.stop()
+		// NOTE(marius): when the collection reports its total items, we use it, with the size of its pages,
+		//  to find how many pages are left, so we don't prefetch past the last one.
+		total, size, consumed := 0, 0, 0
+
+		cur := start
+		for !vocab.IsNil(cur) {
+			if err := ctx.Err(); err != nil {
+				yield(Page{IRI: cur.GetLink()}, err)
+				return
+			}
+			if id := cur.GetLink(); len(id) > 0 {
+				if _, ok := visited[id]; ok {
+					c.l.WithContext(Ctx{"iri": id}).Warnf("collection page was already visited")
+					return
+				}
+				visited[id] = struct{}{}
+			}
+
+			col, err := pf.load(cur)
+			if err != nil {
+				yield(Page{IRI: cur.GetLink()}, err)
+				return
+			}
+
+			if total == 0 {
+				total = totalItems(col)
+			}
+			consumed += len(col.Collection())
+			if size == 0 && !isCollection(col) {
+				size = len(col.Collection())
+			}
+
+			next := dir.follow(col)
+			p := Page{IRI: cur.GetLink()}
+			if !vocab.IsNil(next) {
+				p.Next = next.GetLink()
+				if vocab.IsIRI(next) {
+					remaining := -1
+					if total > 0 && size > 0 {
+						remaining = max((total-consumed+size-1)/size, 1)
+					}
+					pf.schedule(p.IRI, p.Next, remaining)
+				}
+			}
+			if isCollection(col) {
+				// NOTE(marius): when walking backwards, the embedded items are expected to be part of the first page,
+				// which we reach last, so we only use them if the collection doesn't have any pages.
+				if dir == Forward || vocab.IsNil(next) {
+					seen = col.Collection()
+					p.Items = append(p.Items, seen...)
+				}
+			} else {
+				for _, it := range col.Collection() {
+					if !seen.Contains(it) {
+						p.Items = append(p.Items, it)
+					}
+				}
+			}
+			if dir == Backward {
+				slices.Reverse(p.Items)
+			}
+			if !yield(p, nil) {
+				return
+			}
+			cur = next
+		}
+	}
+}
+
+// prefetched is the result of loading a collection page in the background.
+type prefetched struct {
+	done   chan struct{}
+	cancel context.CancelFunc
+	col    vocab.CollectionInterface
+	err    error
+}
+
+// prefetcher loads collection pages ahead of the one being consumed by a walk.
+// If the client doesn't have prefetching enabled, it loads every page only when it's requested.
+type prefetcher struct {
+	c       C
+	ctx     context.Context
+	stopFn  context.CancelFunc
+	pending map[vocab.IRI]*prefetched
+	// guess is the page which we predicted to follow the last scheduled one.
+	guess vocab.IRI
+	ff    []filters.Check
+}
+
+func (c C) prefetcher(ctx context.Context, ff ...filters.Check) *prefetcher {
+	ctx, stopFn := context.WithCancel(ctx)
+	return &prefetcher{c: c, ctx: ctx, stopFn: stopFn, pending: make(map[vocab.IRI]*prefetched), ff: ff}
+}
+
+// stop cancels the loading of the pages which haven't been consumed.
+func (p *prefetcher) stop() {
+	p.stopFn()
+}
+
+// load returns the page it [vocab.Item], waiting for it if it's being prefetched, or loading it otherwise.
+func (p *prefetcher) load(it vocab.Item) (vocab.CollectionInterface, error) {
+	if f, ok := p.pending[it.GetLink()]; ok && vocab.IsIRI(it) {
+		delete(p.pending, it.GetLink())
+		defer f.cancel()
+		select {
+		case <-f.done:
+			return f.col, f.err
+		case <-p.ctx.Done():
+			return nil, p.ctx.Err()
+		}
+	}
+	return p.page(p.ctx, it)
+}
+
+func (p *prefetcher) page(ctx context.Context, it vocab.Item) (vocab.CollectionInterface, error) {
+	if !vocab.IsIRI(it) {
+		return p.c.page(ctx, it, p.ff...)
+	}
+	host := hostOf(it.GetLink())
+	if err := p.c.prefetchHost.acquire(ctx, host); err != nil {
+		return nil, err
+	}
+	defer p.c.prefetchHost.release(host)
+	return p.c.page(ctx, it, p.ff...)
+}
+
+// schedule starts loading in the background the next page, and the ones that we can predict after it.
+// The remaining value is the number of pages left in the collection, or -1 if we don't know it, in which case
+// we prefetch the predicted pages only after the server confirmed our previous prediction.
+func (p *prefetcher) schedule(cur, next vocab.IRI, remaining int) {
+	if p.c.prefetch <= 0 {
+		return
+	}
+	window := predictPages(cur, next, max(p.c.prefetch, 2))
+	confirmed := len(p.guess) > 0 && next.Equal(p.guess)
+	p.guess = ""
+	if len(window) > 1 {
+		p.guess = window[1]
+	}
+	n := remaining
+	if n < 0 {
+		n = 1
+		if confirmed {
+			n = p.c.prefetch
+		}
+	}
+	window = window[:min(len(window), n, p.c.prefetch)]
+
+	for iri, f := range p.pending {
+		// NOTE(marius): the pages we predicted previously that are not in the current window are not needed anymore.
+		if !window.Contains(iri) {
+			f.cancel()
+			delete(p.pending, iri)
+		}
+	}
+	for _, iri := range window {
+		if _, ok := p.pending[iri]; ok {
+			continue
+		}
+		ctx, cancel := context.WithCancel(p.ctx)
+		f := &prefetched{done: make(chan struct{}), cancel: cancel}
+		p.pending[iri] = f
+		go func() {
+			defer close(f.done)
+			f.col, f.err = p.page(ctx, iri)
+		}()
+	}
+}
+
+// predictPages returns the next IRI, followed by the IRIs of the pages that come after it, up to a total of n.
+// The IRIs can be predicted only when cur and next differ by a single numeric query parameter,
+// in which case we continue incrementing it with the same step.
+func predictPages(cur, next vocab.IRI, n int) vocab.IRIs {
+	result := vocab.IRIs{next}
+	cu, err := cur.URL()
+	if err != nil {
+		return result
+	}
+	nu, err := next.URL()
+	if err != nil {
+		return result
+	}
+	if cu.Scheme != nu.Scheme || cu.Host != nu.Host || cu.Path != nu.Path {
+		return result
+	}
+	cq := strings.Split(cu.RawQuery, "&")
+	nq := strings.Split(nu.RawQuery, "&")
+	if len(cq) != len(nq) {
+		return result
+	}
+
+	pos, last, step := -1, 0, 0
+	for i := range nq {
+		if cq[i] == nq[i] {
+			continue
+		}
+		ck, cv, _ := strings.Cut(cq[i], "=")
+		nk, nv, _ := strings.Cut(nq[i], "=")
+		if pos >= 0 || ck != nk {
+			return result
+		}
+		cn, err := strconv.Atoi(cv)
+		if err != nil {
+			return result
+		}
+		nn, err := strconv.Atoi(nv)
+		if err != nil || nn <= cn {
+			return result
+		}
+		pos, last, step = i, nn, nn-cn
+	}
+	if pos < 0 {
+		return result
+	}
+
+	key, _, _ := strings.Cut(nq[pos], "=")
+	for i := 1; i < n; i++ {
+		nq[pos] = key + "=" + strconv.Itoa(last+i*step)
+		nu.RawQuery = strings.Join(nq, "&")
+		result = append(result, vocab.IRI(nu.String()))
+	}
+	return result
+}
+
+// totalItems returns the number of items that the col collection reports, zero if it doesn't.
+func totalItems(col vocab.CollectionInterface) int {
+	switch c := col.(type) {
+	case *vocab.OrderedCollection:
+		return int(c.TotalItems)
+	case *vocab.OrderedCollectionPage:
+		return int(c.TotalItems)
+	case *vocab.Collection:
+		return int(c.TotalItems)
+	case *vocab.CollectionPage:
+		return int(c.TotalItems)
+	}
+	return 0
+}
+
+func hostOf(iri vocab.IRI) string {
+	u, err := iri.URL()
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
+
+// page loads the it [vocab.Item] as a collection, or collection page, if it's an IRI,
+// or converts it directly if it has been embedded in the previous document.
+func (c C) page(ctx context.Context, it vocab.Item, ff ...filters.Check) (vocab.CollectionInterface, error) {
+	if vocab.IsIRI(it) {
+		return c.collection(ctx, it.GetLink(), ff...)
+	}
+	col, err := toCollectionInterface(it)
+	if err != nil {
+		return nil, err
+	}
+	return c.filter(ctx, it.GetLink(), col, ff...), nil
+}
+
+func isCollection(col vocab.CollectionInterface) bool {
+	switch col.(type) {
+	case *vocab.OrderedCollection, *vocab.Collection:
+		return true
+	}
+	return false
+}
+
+// follow returns the page that comes after col when walking a collection in the d [Direction].
+func (d Direction) follow(col vocab.CollectionInterface) vocab.Item {
+	if d == Backward {
+		return prevPage(col)
+	}
+	return nextPage(col)
+}
+
+// nextPage returns the page that follows col when walking a collection from start to end:
+// the "first" page for collections, and the "next" page for collection pages.
+func nextPage(col vocab.CollectionInterface) vocab.Item {
+	switch cc := col.(type) {
+	case *vocab.OrderedCollection:
+		return cc.First
+	case *vocab.Collection:
+		return cc.First
+	case *vocab.OrderedCollectionPage:
+		return cc.Next
+	case *vocab.CollectionPage:
+		return cc.Next
+	}
+	return nil
+}
+
+// prevPage returns the page that follows col when walking a collection from end to start:
+// the "last" page for collections, and the "prev" page for collection pages.
+func prevPage(col vocab.CollectionInterface) vocab.Item {
+	switch cc := col.(type) {
+	case *vocab.OrderedCollection:
+		return cc.Last
+	case *vocab.Collection:
+		return cc.Last
+	case *vocab.OrderedCollectionPage:
+		return cc.Prev
+	case *vocab.CollectionPage:
+		return cc.Prev
+	}
+	return nil
+}
+
 func irif(i vocab.IRI, f ...filters.Check) vocab.IRI {
 	return vocab.IRI(string(i) + FilterQueryString(f...))
 }
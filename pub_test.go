@@ -2,10 +2,13 @@ package client
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"path/filepath"
+	"sync"
 	"testing"
+	"time"
 
 	"git.sr.ht/~mariusor/lw"
 	vocab "github.com/go-ap/activitypub"
@@ -1519,3 +1522,676 @@ func TestActivityActorTargetCollections(t *testing.T) {
 		})
 	}
 }
+
+func mockPagesHandler(pages map[string]vocab.Item) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		it, ok := pages[r.URL.RequestURI()]
+		if !ok {
+			errors.NotFound.ServeHTTP(w, r)
+			return
+		}
+		raw, _ := vocab.MarshalJSON(it)
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(raw)
+	}
+}
+
+func mockItems(ids ...vocab.IRI) vocab.ItemCollection {
+	items := make(vocab.ItemCollection, 0, len(ids))
+	for _, id := range ids {
+		items = append(items, &vocab.Object{ID: id, Type: vocab.NoteType})
+	}
+	return items
+}
+
+func TestC_Items(t *testing.T) {
+	tests := []struct {
+		name    string
+		iri     vocab.IRI
+		pages   map[string]vocab.Item
+		cancel  bool
+		want    vocab.ItemCollection
+		wantErr error
+	}{
+		{
+			name:    "not found",
+			iri:     "http://example.com/inbox",
+			wantErr: errors.Annotatef(errf("invalid status received").iri("http://example.com/inbox").annotate(errors.NotFoundf("/inbox not found")), "unable to load"),
+		},
+		{
+			name: "ordered collection with embedded items",
+			iri:  "http://example.com/inbox",
+			pages: map[string]vocab.Item{
+				"/inbox": &vocab.OrderedCollection{
+					ID:           "http://example.com/inbox",
+					Type:         vocab.OrderedCollectionType,
+					OrderedItems: mockItems("http://example.com/1", "http://example.com/2"),
+				},
+			},
+			want: mockItems("http://example.com/1", "http://example.com/2"),
+		},
+		{
+			name: "ordered collection with pages",
+			iri:  "http://example.com/inbox",
+			pages: map[string]vocab.Item{
+				"/inbox": &vocab.OrderedCollection{
+					ID:    "http://example.com/inbox",
+					Type:  vocab.OrderedCollectionType,
+					First: vocab.IRI("http://example.com/inbox?page=1"),
+				},
+				"/inbox?page=1": &vocab.OrderedCollectionPage{
+					ID:           "http://example.com/inbox?page=1",
+					Type:         vocab.OrderedCollectionPageType,
+					Next:         vocab.IRI("http://example.com/inbox?page=2"),
+					OrderedItems: mockItems("http://example.com/1", "http://example.com/2"),
+				},
+				"/inbox?page=2": &vocab.OrderedCollectionPage{
+					ID:           "http://example.com/inbox?page=2",
+					Type:         vocab.OrderedCollectionPageType,
+					OrderedItems: mockItems("http://example.com/3"),
+				},
+			},
+			want: mockItems("http://example.com/1", "http://example.com/2", "http://example.com/3"),
+		},
+		{
+			name: "collection with embedded first page",
+			iri:  "http://example.com/followers",
+			pages: map[string]vocab.Item{
+				"/followers": &vocab.Collection{
+					ID:   "http://example.com/followers",
+					Type: vocab.CollectionType,
+					First: &vocab.CollectionPage{
+						ID:    "http://example.com/followers?page=1",
+						Type:  vocab.CollectionPageType,
+						Next:  vocab.IRI("http://example.com/followers?page=2"),
+						Items: mockItems("http://example.com/1"),
+					},
+				},
+				"/followers?page=2": &vocab.CollectionPage{
+					ID:    "http://example.com/followers?page=2",
+					Type:  vocab.CollectionPageType,
+					Items: mockItems("http://example.com/2"),
+				},
+			},
+			want: mockItems("http://example.com/1", "http://example.com/2"),
+		},
+		{
+			name: "collection items are repeated in the first page",
+			iri:  "http://example.com/inbox",
+			pages: map[string]vocab.Item{
+				"/inbox": &vocab.OrderedCollection{
+					ID:           "http://example.com/inbox",
+					Type:         vocab.OrderedCollectionType,
+					First:        vocab.IRI("http://example.com/inbox?page=1"),
+					OrderedItems: mockItems("http://example.com/1"),
+				},
+				"/inbox?page=1": &vocab.OrderedCollectionPage{
+					ID:           "http://example.com/inbox?page=1",
+					Type:         vocab.OrderedCollectionPageType,
+					OrderedItems: mockItems("http://example.com/1", "http://example.com/2"),
+				},
+			},
+			want: mockItems("http://example.com/1", "http://example.com/2"),
+		},
+		{
+			name: "next points to an already visited page",
+			iri:  "http://example.com/inbox",
+			pages: map[string]vocab.Item{
+				"/inbox": &vocab.OrderedCollection{
+					ID:    "http://example.com/inbox",
+					Type:  vocab.OrderedCollectionType,
+					First: vocab.IRI("http://example.com/inbox?page=1"),
+				},
+				"/inbox?page=1": &vocab.OrderedCollectionPage{
+					ID:           "http://example.com/inbox?page=1",
+					Type:         vocab.OrderedCollectionPageType,
+					Next:         vocab.IRI("http://example.com/inbox?page=2"),
+					OrderedItems: mockItems("http://example.com/1"),
+				},
+				"/inbox?page=2": &vocab.OrderedCollectionPage{
+					ID:           "http://example.com/inbox?page=2",
+					Type:         vocab.OrderedCollectionPageType,
+					Next:         vocab.IRI("http://example.com/inbox?page=1"),
+					OrderedItems: mockItems("http://example.com/2"),
+				},
+			},
+			want: mockItems("http://example.com/1", "http://example.com/2"),
+		},
+		{
+			name: "failing next page",
+			iri:  "http://example.com/inbox",
+			pages: map[string]vocab.Item{
+				"/inbox": &vocab.OrderedCollectionPage{
+					ID:           "http://example.com/inbox",
+					Type:         vocab.OrderedCollectionPageType,
+					Next:         vocab.IRI("http://example.com/inbox?page=2"),
+					OrderedItems: mockItems("http://example.com/1"),
+				},
+			},
+			want:    mockItems("http://example.com/1"),
+			wantErr: errors.Annotatef(errf("invalid status received").iri("http://example.com/inbox?page=2").annotate(errors.NotFoundf("/inbox not found")), "unable to load"),
+		},
+		{
+			name:   "canceled context",
+			iri:    "http://example.com/inbox",
+			cancel: true,
+			pages: map[string]vocab.Item{
+				"/inbox": &vocab.OrderedCollection{
+					ID:           "http://example.com/inbox",
+					Type:         vocab.OrderedCollectionType,
+					OrderedItems: mockItems("http://example.com/1"),
+				},
+			},
+			wantErr: context.Canceled,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(mockPagesHandler(tt.pages))
+			defer srv.Close()
+
+			c := C{
+				c: srv.Client(),
+				l: lw.Dev(lw.SetOutput(t.Output())),
+			}
+
+			ctx, cancelFn := context.WithCancel(context.Background())
+			defer cancelFn()
+			if tt.cancel {
+				cancelFn()
+			}
+
+			var got vocab.ItemCollection
+			var err error
+			for it, e := range c.Items(ctx, tt.iri) {
+				if e != nil {
+					err = e
+					break
+				}
+				got = append(got, it)
+			}
+			if !cmp.Equal(err, tt.wantErr, EquateWeakErrors(srv.URL)) {
+				t.Errorf("Items() error = %s", cmp.Diff(tt.wantErr, err, EquateWeakErrors(srv.URL)))
+			}
+			if !cmp.Equal(got, tt.want, EquateItems) {
+				t.Errorf("Items() got = %s", cmp.Diff(tt.want, got, EquateItems))
+			}
+		})
+	}
+}
+
+func mockPagedCollection() map[string]vocab.Item {
+	return map[string]vocab.Item{
+		"/outbox": &vocab.OrderedCollection{
+			ID:           "http://example.com/outbox",
+			Type:         vocab.OrderedCollectionType,
+			First:        vocab.IRI("http://example.com/outbox?page=1"),
+			Last:         vocab.IRI("http://example.com/outbox?page=2"),
+			OrderedItems: mockItems("http://example.com/4", "http://example.com/3"),
+		},
+		"/outbox?page=1": &vocab.OrderedCollectionPage{
+			ID:           "http://example.com/outbox?page=1",
+			Type:         vocab.OrderedCollectionPageType,
+			Next:         vocab.IRI("http://example.com/outbox?page=2"),
+			OrderedItems: mockItems("http://example.com/4", "http://example.com/3"),
+		},
+		"/outbox?page=2": &vocab.OrderedCollectionPage{
+			ID:           "http://example.com/outbox?page=2",
+			Type:         vocab.OrderedCollectionPageType,
+			Prev:         vocab.IRI("http://example.com/outbox?page=1"),
+			OrderedItems: mockItems("http://example.com/2", "http://example.com/1"),
+		},
+	}
+}
+
+func TestC_Pages(t *testing.T) {
+	tests := []struct {
+		name    string
+		iri     vocab.IRI
+		dir     Direction
+		want    []Page
+		wantErr error
+	}{
+		{
+			name: "forward",
+			iri:  "http://example.com/outbox",
+			dir:  Forward,
+			want: []Page{
+				{
+					IRI:   "http://example.com/outbox",
+					Next:  "http://example.com/outbox?page=1",
+					Items: mockItems("http://example.com/4", "http://example.com/3"),
+				},
+				{
+					IRI:  "http://example.com/outbox?page=1",
+					Next: "http://example.com/outbox?page=2",
+				},
+				{
+					IRI:   "http://example.com/outbox?page=2",
+					Items: mockItems("http://example.com/2", "http://example.com/1"),
+				},
+			},
+		},
+		{
+			name: "backward",
+			iri:  "http://example.com/outbox",
+			dir:  Backward,
+			want: []Page{
+				{
+					IRI:  "http://example.com/outbox",
+					Next: "http://example.com/outbox?page=2",
+				},
+				{
+					IRI:   "http://example.com/outbox?page=2",
+					Next:  "http://example.com/outbox?page=1",
+					Items: mockItems("http://example.com/1", "http://example.com/2"),
+				},
+				{
+					IRI:   "http://example.com/outbox?page=1",
+					Items: mockItems("http://example.com/3", "http://example.com/4"),
+				},
+			},
+		},
+		{
+			name: "forward resumed from page",
+			iri:  "http://example.com/outbox?page=2",
+			dir:  Forward,
+			want: []Page{
+				{
+					IRI:   "http://example.com/outbox?page=2",
+					Items: mockItems("http://example.com/2", "http://example.com/1"),
+				},
+			},
+		},
+		{
+			name: "backward resumed from page",
+			iri:  "http://example.com/outbox?page=2",
+			dir:  Backward,
+			want: []Page{
+				{
+					IRI:   "http://example.com/outbox?page=2",
+					Next:  "http://example.com/outbox?page=1",
+					Items: mockItems("http://example.com/1", "http://example.com/2"),
+				},
+				{
+					IRI:   "http://example.com/outbox?page=1",
+					Items: mockItems("http://example.com/3", "http://example.com/4"),
+				},
+			},
+		},
+		{
+			name: "missing page",
+			iri:  "http://example.com/outbox?page=3",
+			dir:  Backward,
+			want: []Page{
+				{IRI: "http://example.com/outbox?page=3"},
+			},
+			wantErr: errors.Annotatef(errf("invalid status received").iri("http://example.com/outbox?page=3").annotate(errors.NotFoundf("/outbox not found")), "unable to load"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(mockPagesHandler(mockPagedCollection()))
+			defer srv.Close()
+
+			c := C{
+				c: srv.Client(),
+				l: lw.Dev(lw.SetOutput(t.Output())),
+			}
+
+			var got []Page
+			var err error
+			for p, e := range c.Pages(context.Background(), tt.iri, tt.dir) {
+				got = append(got, p)
+				if e != nil {
+					err = e
+					break
+				}
+			}
+			if !cmp.Equal(err, tt.wantErr, EquateWeakErrors(srv.URL)) {
+				t.Errorf("Pages() error = %s", cmp.Diff(tt.wantErr, err, EquateWeakErrors(srv.URL)))
+			}
+			if !cmp.Equal(got, tt.want, EquateItems) {
+				t.Errorf("Pages() got = %s", cmp.Diff(tt.want, got, EquateItems))
+			}
+		})
+	}
+}
+
+func Test_predictPages(t *testing.T) {
+	tests := []struct {
+		name string
+		cur  vocab.IRI
+		next vocab.IRI
+		n    int
+		want vocab.IRIs
+	}{
+		{
+			name: "collection to first page",
+			cur:  "http://example.com/outbox",
+			next: "http://example.com/outbox?page=1",
+			n:    3,
+			want: vocab.IRIs{"http://example.com/outbox?page=1"},
+		},
+		{
+			name: "incrementing page",
+			cur:  "http://example.com/outbox?page=1",
+			next: "http://example.com/outbox?page=2",
+			n:    3,
+			want: vocab.IRIs{"http://example.com/outbox?page=2", "http://example.com/outbox?page=3", "http://example.com/outbox?page=4"},
+		},
+		{
+			name: "incrementing offset with other parameters",
+			cur:  "http://example.com/outbox?type=Create&offset=0&max=20",
+			next: "http://example.com/outbox?type=Create&offset=20&max=20",
+			n:    2,
+			want: vocab.IRIs{"http://example.com/outbox?type=Create&offset=20&max=20", "http://example.com/outbox?type=Create&offset=40&max=20"},
+		},
+		{
+			name: "opaque cursor",
+			cur:  "http://example.com/outbox?max_id=1234",
+			next: "http://example.com/outbox?max_id=abcd",
+			n:    3,
+			want: vocab.IRIs{"http://example.com/outbox?max_id=abcd"},
+		},
+		{
+			name: "decreasing values",
+			cur:  "http://example.com/outbox?max_id=1234",
+			next: "http://example.com/outbox?max_id=1200",
+			n:    3,
+			want: vocab.IRIs{"http://example.com/outbox?max_id=1200"},
+		},
+		{
+			name: "more than one changed parameter",
+			cur:  "http://example.com/outbox?page=1&min=1",
+			next: "http://example.com/outbox?page=2&min=2",
+			n:    3,
+			want: vocab.IRIs{"http://example.com/outbox?page=2&min=2"},
+		},
+		{
+			name: "different host",
+			cur:  "http://example.com/outbox?page=1",
+			next: "http://social.example.com/outbox?page=2",
+			n:    3,
+			want: vocab.IRIs{"http://social.example.com/outbox?page=2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := predictPages(tt.cur, tt.next, tt.n); !cmp.Equal(got, tt.want) {
+				t.Errorf("predictPages() = %s", cmp.Diff(tt.want, got))
+			}
+		})
+	}
+}
+
+func TestC_Items_withPrefetch(t *testing.T) {
+	tests := []struct {
+		name       string
+		pageCount  int
+		totalItems uint
+	}{
+		{
+			name:       "with total items",
+			pageCount:  6,
+			totalItems: 6,
+		},
+		{
+			name:      "without total items",
+			pageCount: 6,
+		},
+		{
+			name:      "fewer pages than prefetched",
+			pageCount: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pages := map[string]vocab.Item{
+				"/outbox": &vocab.OrderedCollection{
+					ID:         "http://example.com/outbox",
+					Type:       vocab.OrderedCollectionType,
+					First:      vocab.IRI("http://example.com/outbox?page=1"),
+					TotalItems: tt.totalItems,
+				},
+			}
+			var want vocab.ItemCollection
+			for i := 1; i <= tt.pageCount; i++ {
+				id := vocab.IRI(fmt.Sprintf("http://example.com/outbox?page=%d", i))
+				p := &vocab.OrderedCollectionPage{
+					ID:           id,
+					Type:         vocab.OrderedCollectionPageType,
+					OrderedItems: mockItems(vocab.IRI(fmt.Sprintf("http://example.com/%d", i))),
+				}
+				if i < tt.pageCount {
+					p.Next = vocab.IRI(fmt.Sprintf("http://example.com/outbox?page=%d", i+1))
+				}
+				pages[fmt.Sprintf("/outbox?page=%d", i)] = p
+				want = append(want, p.OrderedItems...)
+			}
+
+			perHost := 2
+			var mu sync.Mutex
+			active, maxActive := 0, 0
+			missing := make([]string, 0)
+			handler := mockPagesHandler(pages)
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				active++
+				maxActive = max(maxActive, active)
+				if _, ok := pages[r.URL.RequestURI()]; !ok {
+					missing = append(missing, r.URL.RequestURI())
+				}
+				mu.Unlock()
+
+				time.Sleep(10 * time.Millisecond)
+				handler(w, r)
+
+				mu.Lock()
+				active--
+				mu.Unlock()
+			}))
+			defer srv.Close()
+
+			c := New(WithHTTPClient(srv.Client()), WithLogger(lw.Dev(lw.SetOutput(t.Output()))), WithPrefetch(4, perHost))
+
+			var got vocab.ItemCollection
+			for it, err := range c.Items(context.Background(), "http://example.com/outbox") {
+				if err != nil {
+					t.Fatalf("Items() error = %s", err)
+				}
+				got = append(got, it)
+			}
+			if !cmp.Equal(got, want, EquateItems) {
+				t.Errorf("Items() got = %s", cmp.Diff(want, got, EquateItems))
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if maxActive > perHost {
+				t.Errorf("Items() concurrent requests %d, expected at most %d", maxActive, perHost)
+			}
+			if tt.totalItems > 0 || tt.pageCount < 3 {
+				if len(missing) > 0 {
+					t.Errorf("Items() prefetched pages past the end of the collection: %v", missing)
+				}
+			}
+		})
+	}
+}
+
+func TestC_Collection_withLocalFiltering(t *testing.T) {
+	mixed := vocab.ItemCollection{
+		&vocab.Object{ID: "http://example.com/1", Type: vocab.NoteType},
+		&vocab.Object{ID: "http://example.com/2", Type: vocab.ArticleType},
+		vocab.IRI("http://example.com/3"),
+	}
+	tests := []struct {
+		name   string
+		local  bool
+		col    vocab.Item
+		ff     []filters.Check
+		want   vocab.CollectionInterface
+		wantBy []FilteredBy
+	}{
+		{
+			name:   "no local filtering",
+			col:    &vocab.OrderedCollection{ID: "http://example.com/inbox", Type: vocab.OrderedCollectionType, OrderedItems: mixed},
+			ff:     []filters.Check{filters.HasType(vocab.NoteType)},
+			want:   &vocab.OrderedCollection{ID: "http://example.com/inbox", Type: vocab.OrderedCollectionType, OrderedItems: mixed},
+			wantBy: nil,
+		},
+		{
+			name:   "no filters",
+			local:  true,
+			col:    &vocab.OrderedCollection{ID: "http://example.com/inbox", Type: vocab.OrderedCollectionType, OrderedItems: mixed},
+			want:   &vocab.OrderedCollection{ID: "http://example.com/inbox", Type: vocab.OrderedCollectionType, OrderedItems: mixed},
+			wantBy: nil,
+		},
+		{
+			name:  "filtered by client",
+			local: true,
+			col:   &vocab.OrderedCollection{ID: "http://example.com/inbox", Type: vocab.OrderedCollectionType, OrderedItems: mixed},
+			ff:    []filters.Check{filters.HasType(vocab.NoteType)},
+			want: &vocab.OrderedCollection{ID: "http://example.com/inbox", Type: vocab.OrderedCollectionType, OrderedItems: vocab.ItemCollection{
+				&vocab.Object{ID: "http://example.com/1", Type: vocab.NoteType},
+				vocab.IRI("http://example.com/3"),
+			}},
+			wantBy: []FilteredBy{FilteredByClient},
+		},
+		{
+			name:   "filtered by server",
+			local:  true,
+			col:    &vocab.CollectionPage{ID: "http://example.com/inbox", Type: vocab.CollectionPageType, Items: mockItems("http://example.com/1")},
+			ff:     []filters.Check{filters.HasType(vocab.NoteType)},
+			want:   &vocab.CollectionPage{ID: "http://example.com/inbox", Type: vocab.CollectionPageType, Items: mockItems("http://example.com/1")},
+			wantBy: []FilteredBy{FilteredByServer},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				raw, _ := vocab.MarshalJSON(tt.col)
+				w.WriteHeader(http.StatusOK)
+				_, _ = w.Write(raw)
+			}))
+			defer srv.Close()
+
+			opts := []OptionFn{WithHTTPClient(srv.Client()), WithLogger(lw.Dev(lw.SetOutput(t.Output())))}
+			if tt.local {
+				opts = append(opts, WithLocalFiltering())
+			}
+			c := New(opts...)
+
+			var gotBy []FilteredBy
+			ctx := ReportFiltering(context.Background(), func(_ vocab.IRI, by FilteredBy) {
+				gotBy = append(gotBy, by)
+			})
+			got, err := c.Collection(ctx, "http://example.com/inbox", tt.ff...)
+			if err != nil {
+				t.Fatalf("Collection() error = %s", err)
+			}
+			if !cmp.Equal(got, tt.want, EquateItems) {
+				t.Errorf("Collection() got = %s", cmp.Diff(tt.want, got, EquateItems))
+			}
+			if !cmp.Equal(gotBy, tt.wantBy) {
+				t.Errorf("Collection() filtered by = %s", cmp.Diff(tt.wantBy, gotBy))
+			}
+		})
+	}
+}
+
+func TestC_Collection_concurrentLocalFiltering(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		raw, _ := vocab.MarshalJSON(&vocab.OrderedCollection{
+			ID:   "http://example.com/inbox",
+			Type: vocab.OrderedCollectionType,
+			OrderedItems: vocab.ItemCollection{
+				&vocab.Object{ID: "http://example.com/1", Type: vocab.NoteType},
+				&vocab.Object{ID: "http://example.com/2", Type: vocab.ArticleType},
+			},
+		})
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(raw)
+	}))
+	defer srv.Close()
+
+	c := New(WithHTTPClient(srv.Client()), WithLogger(lw.Dev(lw.SetOutput(t.Output()))), WithLocalFiltering())
+
+	types := []vocab.ActivityVocabularyType{vocab.NoteType, vocab.ArticleType}
+	got := make([]vocab.CollectionInterface, len(types))
+	wg := sync.WaitGroup{}
+	for i, typ := range types {
+		wg.Go(func() {
+			col, err := c.Collection(context.Background(), "http://example.com/inbox", filters.HasType(typ))
+			if err != nil {
+				t.Errorf("Collection() error = %s", err)
+			}
+			got[i] = col
+		})
+	}
+	// NOTE(marius): give both calls the chance to share the same load
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	for i, typ := range types {
+		if got[i] == nil {
+			continue
+		}
+		items := got[i].Collection()
+		if len(items) != 1 || items[0].GetType() != typ {
+			t.Errorf("Collection() filtered by %s = %v", typ, items)
+		}
+	}
+}
+
+func TestC_Items_withLocalFiltering(t *testing.T) {
+	pages := map[string]vocab.Item{
+		"/outbox": &vocab.OrderedCollectionPage{
+			ID:   "http://example.com/outbox",
+			Type: vocab.OrderedCollectionPageType,
+			Next: vocab.IRI("http://example.com/outbox?page=2"),
+			OrderedItems: vocab.ItemCollection{
+				&vocab.Object{ID: "http://example.com/1", Type: vocab.NoteType},
+				&vocab.Object{ID: "http://example.com/2", Type: vocab.ArticleType},
+			},
+		},
+		"/outbox?page=2": &vocab.OrderedCollectionPage{
+			ID:   "http://example.com/outbox?page=2",
+			Type: vocab.OrderedCollectionPageType,
+			OrderedItems: vocab.ItemCollection{
+				&vocab.Object{ID: "http://example.com/3", Type: vocab.ArticleType},
+				&vocab.Object{ID: "http://example.com/4", Type: vocab.NoteType},
+			},
+		},
+	}
+	handler := mockPagesHandler(pages)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// NOTE(marius): we ignore the filters in the query parameters, like most servers do
+		if !r.URL.Query().Has("page") {
+			r.URL.RawQuery = ""
+			r.RequestURI = r.URL.Path
+		}
+		handler(w, r)
+	}))
+	defer srv.Close()
+
+	c := New(WithHTTPClient(srv.Client()), WithLogger(lw.Dev(lw.SetOutput(t.Output()))), WithLocalFiltering())
+
+	want := vocab.ItemCollection{
+		&vocab.Object{ID: "http://example.com/1", Type: vocab.NoteType},
+		&vocab.Object{ID: "http://example.com/4", Type: vocab.NoteType},
+	}
+	var got vocab.ItemCollection
+	for it, err := range c.Items(context.Background(), "http://example.com/outbox", filters.HasType(vocab.NoteType)) {
+		if err != nil {
+			t.Fatalf("Items() error = %s", err)
+		}
+		got = append(got, it)
+	}
+	if !cmp.Equal(got, want, EquateItems) {
+		t.Errorf("Items() got = %s", cmp.Diff(want, got, EquateItems))
+	}
+}
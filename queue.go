@@ -0,0 +1,303 @@
+package client
+
+import (
+	"context"
+	"crypto/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	vocab "github.com/go-ap/activitypub"
+	"github.com/go-ap/errors"
+	"github.com/go-ap/jsonld"
+)
+
+// DefaultRetrySchedule are the delays between the delivery attempts of a [Queue], which add up to about four days.
+var DefaultRetrySchedule = []time.Duration{
+	time.Minute,
+	5 * time.Minute,
+	30 * time.Minute,
+	2 * time.Hour,
+	6 * time.Hour,
+	12 * time.Hour,
+	24 * time.Hour,
+	48 * time.Hour,
+}
+
+const (
+	defaultQueueWorkers = 4
+	defaultQueuePoll    = 10 * time.Second
+)
+
+// Queue posts activities to collections in the background, as [C.CtxToCollection] would, and it retries
+// the deliveries that fail because of network errors or server errors, following a retry schedule.
+// The deliveries that fail with 4xx response statuses, which are permanent, and the ones that
+// still fail at the end of the retry schedule, are moved to the dead letters of the [QueueStore].
+type Queue struct {
+	c        C
+	store    QueueStore
+	schedule []time.Duration
+	workers  int
+	poll     time.Duration
+	deadFns  []func(QueueItem)
+
+	mu       sync.Mutex
+	inflight map[string]struct{}
+	wake     chan struct{}
+	cancel   context.CancelFunc
+	abort    context.CancelFunc
+	done     chan struct{}
+}
+
+// QueueOptionFn is the type designating setup functions accepted by the [C.NewQueue] initializer.
+type QueueOptionFn func(*Queue)
+
+// WithQueueWorkers sets the number of deliveries that the queue does concurrently.
+func WithQueueWorkers(n int) QueueOptionFn {
+	return func(q *Queue) {
+		q.workers = max(n, 1)
+	}
+}
+
+// WithRetrySchedule sets the delays between the delivery attempts of an item.
+// The item is moved to the dead letters when it fails after the last one.
+func WithRetrySchedule(delays ...time.Duration) QueueOptionFn {
+	return func(q *Queue) {
+		q.schedule = delays
+	}
+}
+
+// WithQueuePollInterval sets how often the queue checks the store for items that are due for delivery.
+func WithQueuePollInterval(d time.Duration) QueueOptionFn {
+	return func(q *Queue) {
+		q.poll = d
+	}
+}
+
+// WithDeadLetterFn sets functions which get called for every item that is moved to the dead letters.
+func WithDeadLetterFn(fns ...func(QueueItem)) QueueOptionFn {
+	return func(q *Queue) {
+		q.deadFns = append(q.deadFns, fns...)
+	}
+}
+
+// NewQueue creates a delivery [Queue] which uses the client for posting the activities, and the store for keeping them
+// until they are delivered. The queue doesn't deliver anything until it's started with [Queue.Start].
+func (c C) NewQueue(store QueueStore, opts ...QueueOptionFn) *Queue {
+	q := Queue{
+		c:        c,
+		store:    store,
+		schedule: DefaultRetrySchedule,
+		workers:  defaultQueueWorkers,
+		poll:     defaultQueuePoll,
+		inflight: make(map[string]struct{}),
+		wake:     make(chan struct{}, 1),
+	}
+	for _, fn := range opts {
+		fn(&q)
+	}
+	return &q
+}
+
+// Enqueue saves the act activity in the queue, to be delivered to every one of the colIRI collections.
+func (q *Queue) Enqueue(act vocab.Item, colIRI ...vocab.IRI) error {
+	if vocab.IsNil(act) {
+		return errf("invalid nil activity")
+	}
+	cont, err := jsonld.WithContext(jsonld.IRI(vocab.ActivityBaseURI), jsonld.IRI(vocab.SecurityContextURI)).Marshal(act)
+	if err != nil {
+		return errf("unable to marshal activity").iri(act.GetLink()).annotate(err)
+	}
+
+	now := TimeNow()
+	for _, iri := range colIRI {
+		if err = validateIRIForRequest(iri); err != nil {
+			return errf("invalid IRI to POST to").iri(iri).annotate(err)
+		}
+		it := QueueItem{ID: rand.Text(), Target: iri, Activity: cont, Created: now, Next: now}
+		if err = q.store.Put(it); err != nil {
+			return errors.Annotatef(err, "unable to save queue item")
+		}
+	}
+	q.signal()
+	return nil
+}
+
+// Start starts delivering the items of the queue in the background, until [Queue.Drain] is called,
+// or the ctx is done.
+func (q *Queue) Start(ctx context.Context) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.done != nil {
+		return errors.Newf("queue is already running")
+	}
+
+	dispatchCtx, cancel := context.WithCancel(ctx)
+	deliverCtx, abort := context.WithCancel(ctx)
+	work := make(chan QueueItem)
+	done := make(chan struct{})
+
+	wg := sync.WaitGroup{}
+	for range q.workers {
+		wg.Go(func() {
+			for it := range work {
+				q.deliver(deliverCtx, it)
+				q.release(it.ID)
+				q.signal()
+			}
+		})
+	}
+	go func() {
+		q.dispatch(dispatchCtx, work)
+		close(work)
+		wg.Wait()
+		abort()
+		close(done)
+	}()
+
+	q.cancel, q.abort, q.done = cancel, abort, done
+	return nil
+}
+
+// Drain stops the queue from starting new deliveries, and it waits for the ones in progress to finish.
+// If the ctx is done before that, the deliveries in progress are canceled, and their items are left in the store,
+// to be retried when the queue gets started again.
+func (q *Queue) Drain(ctx context.Context) error {
+	q.mu.Lock()
+	cancel, abort, done := q.cancel, q.abort, q.done
+	q.mu.Unlock()
+	if done == nil {
+		return nil
+	}
+
+	var err error
+	cancel()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		abort()
+		<-done
+		err = ctx.Err()
+	}
+
+	q.mu.Lock()
+	q.cancel, q.abort, q.done = nil, nil, nil
+	q.mu.Unlock()
+	return err
+}
+
+// signal wakes up the dispatcher, without waiting for the poll interval.
+func (q *Queue) signal() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (q *Queue) dispatch(ctx context.Context, work chan<- QueueItem) {
+	t := time.NewTicker(q.poll)
+	defer t.Stop()
+	for {
+		due := q.due()
+		for i, it := range due {
+			select {
+			case work <- it:
+			case <-ctx.Done():
+				// NOTE(marius): the items which didn't reach a worker must be picked up again when the queue restarts
+				for _, it := range due[i:] {
+					q.release(it.ID)
+				}
+				return
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-q.wake:
+		case <-t.C:
+		}
+	}
+}
+
+// due returns the items that need to be delivered, and which are not already being delivered.
+func (q *Queue) due() []QueueItem {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	items, err := q.store.Due(TimeNow(), q.workers+len(q.inflight))
+	if err != nil {
+		q.c.l.WithContext(Ctx{"err": err.Error()}).Errorf("unable to load queue items")
+		return nil
+	}
+	due := make([]QueueItem, 0, len(items))
+	for _, it := range items {
+		if _, ok := q.inflight[it.ID]; ok {
+			continue
+		}
+		q.inflight[it.ID] = struct{}{}
+		due = append(due, it)
+	}
+	return due
+}
+
+func (q *Queue) release(id string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.inflight, id)
+}
+
+// permanentFailure returns true for the 4xx statuses, which mean that the delivery won't succeed if retried,
+// with the exception of the ones caused by timeouts and rate limiting.
+func permanentFailure(status int) bool {
+	return status >= http.StatusBadRequest && status < http.StatusInternalServerError &&
+		status != http.StatusRequestTimeout && status != http.StatusTooManyRequests
+}
+
+func (q *Queue) deliver(ctx context.Context, it QueueItem) {
+	lctx := Ctx{"id": it.ID, "target": it.Target, "attempt": it.Attempts + 1}
+
+	status, _, _, err := q.c.postTo(ctx, it.Activity, it.Target)
+	if ctx.Err() != nil {
+		// NOTE(marius): the queue is being drained, so we leave the item as it is, to be retried later
+		return
+	}
+	if err == nil && status < http.StatusBadRequest {
+		if err = q.store.Delete(it.ID); err != nil {
+			q.c.l.WithContext(lctx, Ctx{"err": err.Error()}).Errorf("unable to remove delivered queue item")
+		}
+		return
+	}
+
+	it.Status = status
+	it.Error = ""
+	if err != nil {
+		it.Error = err.Error()
+		lctx["err"] = it.Error
+	}
+	if status > 0 {
+		lctx["status"] = status
+	}
+	it.Attempts++
+	if permanentFailure(status) || it.Attempts > len(q.schedule) {
+		q.c.l.WithContext(lctx).Warnf("delivery failed permanently")
+		q.dead(it)
+		return
+	}
+
+	it.Next = TimeNow().Add(q.schedule[it.Attempts-1])
+	lctx["next"] = it.Next
+	q.c.l.WithContext(lctx).Warnf("delivery failed, retrying later")
+	if err = q.store.Put(it); err != nil {
+		q.c.l.WithContext(lctx, Ctx{"err": err.Error()}).Errorf("unable to save queue item")
+	}
+}
+
+func (q *Queue) dead(it QueueItem) {
+	if err := q.store.Dead(it); err != nil {
+		q.c.l.WithContext(Ctx{"id": it.ID, "err": err.Error()}).Errorf("unable to save dead letter")
+		return
+	}
+	for _, fn := range q.deadFns {
+		fn(it)
+	}
+}
@@ -0,0 +1,255 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"git.sr.ht/~mariusor/lw"
+	vocab "github.com/go-ap/activitypub"
+	"github.com/google/go-cmp/cmp"
+)
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for the queue")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func pendingItems(t *testing.T, s QueueStore) []QueueItem {
+	t.Helper()
+	items, err := s.Due(time.Now().Add(24*time.Hour), 0)
+	if err != nil {
+		t.Fatalf("Due() error = %s", err)
+	}
+	return items
+}
+
+func TestQueue(t *testing.T) {
+	defer func(fn func() time.Time) {
+		TimeNow = fn
+	}(TimeNow)
+	TimeNow = func() time.Time { return time.Now().UTC() }
+
+	act := &vocab.Activity{Type: vocab.LikeType, Actor: vocab.IRI("http://example.com/~jdoe"), Object: vocab.IRI("http://example.com/notes/1")}
+
+	tests := []struct {
+		name      string
+		statuses  map[string][]int
+		schedule  []time.Duration
+		targets   vocab.IRIs
+		wantCalls map[string]int32
+		wantDead  map[vocab.IRI]int
+	}{
+		{
+			name:      "delivered",
+			statuses:  map[string][]int{"/~alice/inbox": {http.StatusCreated}, "/~bob/inbox": {http.StatusAccepted}},
+			targets:   vocab.IRIs{"http://example.com/~alice/inbox", "http://example.com/~bob/inbox"},
+			wantCalls: map[string]int32{"/~alice/inbox": 1, "/~bob/inbox": 1},
+		},
+		{
+			name:      "retried after server errors",
+			statuses:  map[string][]int{"/~alice/inbox": {http.StatusBadGateway, http.StatusTooManyRequests, http.StatusAccepted}},
+			schedule:  []time.Duration{time.Millisecond, time.Millisecond},
+			targets:   vocab.IRIs{"http://example.com/~alice/inbox"},
+			wantCalls: map[string]int32{"/~alice/inbox": 3},
+		},
+		{
+			name:      "permanent failure",
+			statuses:  map[string][]int{"/~alice/inbox": {http.StatusForbidden}, "/~bob/inbox": {http.StatusGone}},
+			targets:   vocab.IRIs{"http://example.com/~alice/inbox", "http://example.com/~bob/inbox"},
+			wantCalls: map[string]int32{"/~alice/inbox": 1, "/~bob/inbox": 1},
+			wantDead:  map[vocab.IRI]int{"http://example.com/~alice/inbox": http.StatusForbidden, "http://example.com/~bob/inbox": http.StatusGone},
+		},
+		{
+			name:      "retry schedule exhausted",
+			statuses:  map[string][]int{"/~alice/inbox": {http.StatusServiceUnavailable}},
+			schedule:  []time.Duration{time.Millisecond, time.Millisecond},
+			targets:   vocab.IRIs{"http://example.com/~alice/inbox"},
+			wantCalls: map[string]int32{"/~alice/inbox": 3},
+			wantDead:  map[vocab.IRI]int{"http://example.com/~alice/inbox": http.StatusServiceUnavailable},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mu := sync.Mutex{}
+			calls := make(map[string]*atomic.Int32)
+			for path := range tt.statuses {
+				calls[path] = new(atomic.Int32)
+			}
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+				statuses, ok := tt.statuses[r.URL.Path]
+				if !ok || r.Method != http.MethodPost {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				n := calls[r.URL.Path].Add(1)
+				w.WriteHeader(statuses[min(int(n), len(statuses))-1])
+			}))
+			defer srv.Close()
+
+			var dead sync.Map
+			store := MemQueueStore()
+			c := C{c: srv.Client(), l: lw.Dev(lw.SetOutput(t.Output()))}
+			q := c.NewQueue(store, WithRetrySchedule(tt.schedule...), WithQueuePollInterval(time.Millisecond), WithDeadLetterFn(func(it QueueItem) {
+				dead.Store(it.Target, it.Status)
+			}))
+
+			if err := q.Enqueue(act, tt.targets...); err != nil {
+				t.Fatalf("Enqueue() error = %s", err)
+			}
+			if err := q.Start(context.Background()); err != nil {
+				t.Fatalf("Start() error = %s", err)
+			}
+			if err := q.Start(context.Background()); err == nil {
+				t.Errorf("Start() expected error for a queue that is already running")
+			}
+			waitFor(t, func() bool {
+				return len(pendingItems(t, store)) == 0
+			})
+			if err := q.Drain(context.Background()); err != nil {
+				t.Errorf("Drain() error = %s", err)
+			}
+
+			got := make(map[string]int32)
+			for path, n := range calls {
+				got[path] = n.Load()
+			}
+			if !cmp.Equal(got, tt.wantCalls) {
+				t.Errorf("Queue calls = %s", cmp.Diff(tt.wantCalls, got))
+			}
+			gotDead := make(map[vocab.IRI]int)
+			dead.Range(func(k, v any) bool {
+				gotDead[k.(vocab.IRI)] = v.(int)
+				return true
+			})
+			if len(tt.wantDead) == 0 {
+				tt.wantDead = map[vocab.IRI]int{}
+			}
+			if !cmp.Equal(gotDead, tt.wantDead) {
+				t.Errorf("Queue dead letters = %s", cmp.Diff(tt.wantDead, gotDead))
+			}
+			letters, _ := store.DeadLetters()
+			if len(letters) != len(tt.wantDead) {
+				t.Errorf("DeadLetters() = %d items, want %d", len(letters), len(tt.wantDead))
+			}
+		})
+	}
+}
+
+func TestQueue_Drain(t *testing.T) {
+	defer func(fn func() time.Time) {
+		TimeNow = fn
+	}(TimeNow)
+	TimeNow = func() time.Time { return time.Now().UTC() }
+
+	started := make(chan struct{}, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// NOTE(marius): the server notices that the client went away only after the body has been read
+		_, _ = io.Copy(io.Discard, r.Body)
+		started <- struct{}{}
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	store := MemQueueStore()
+	c := C{c: srv.Client(), l: lw.Dev(lw.SetOutput(t.Output()))}
+	q := c.NewQueue(store, WithQueuePollInterval(time.Millisecond))
+
+	act := &vocab.Activity{Type: vocab.LikeType, Actor: vocab.IRI("http://example.com/~jdoe"), Object: vocab.IRI("http://example.com/notes/1")}
+	if err := q.Enqueue(act, "http://example.com/~alice/inbox"); err != nil {
+		t.Fatalf("Enqueue() error = %s", err)
+	}
+	if err := q.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %s", err)
+	}
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := q.Drain(ctx); err == nil {
+		t.Errorf("Drain() expected a timeout error")
+	}
+
+	items := pendingItems(t, store)
+	if len(items) != 1 || items[0].Attempts != 0 {
+		t.Errorf("Drain() should leave the interrupted item unchanged in the store, got %v", items)
+	}
+	if err := q.Start(context.Background()); err != nil {
+		t.Errorf("Start() after Drain() error = %s", err)
+	}
+	<-started
+	_ = q.Drain(ctx)
+}
+
+func TestQueue_Drain_pendingBatch(t *testing.T) {
+	defer func(fn func() time.Time) {
+		TimeNow = fn
+	}(TimeNow)
+	TimeNow = func() time.Time { return time.Now().UTC() }
+
+	block := atomic.Bool{}
+	block.Store(true)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		if block.Load() {
+			<-r.Context().Done()
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	store := MemQueueStore()
+	c := C{c: srv.Client(), l: lw.Dev(lw.SetOutput(t.Output()))}
+	q := c.NewQueue(store, WithQueueWorkers(2), WithQueuePollInterval(time.Millisecond))
+
+	act := &vocab.Activity{Type: vocab.LikeType, Actor: vocab.IRI("http://example.com/~jdoe"), Object: vocab.IRI("http://example.com/notes/1")}
+	targets := vocab.IRIs{
+		"http://example.com/~alice/inbox",
+		"http://example.com/~bob/inbox",
+		"http://example.com/~carol/inbox",
+		"http://example.com/~dave/inbox",
+	}
+	if err := q.Enqueue(act, targets...); err != nil {
+		t.Fatalf("Enqueue() error = %s", err)
+	}
+	if err := q.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %s", err)
+	}
+	// NOTE(marius): both workers are busy, and the dispatcher holds the other two items, waiting for one of them
+	waitFor(t, func() bool {
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		return len(q.inflight) == len(targets)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := q.Drain(ctx); err == nil {
+		t.Errorf("Drain() expected a timeout error")
+	}
+
+	block.Store(false)
+	if err := q.Start(context.Background()); err != nil {
+		t.Fatalf("Start() after Drain() error = %s", err)
+	}
+	waitFor(t, func() bool {
+		return len(pendingItems(t, store)) == 0
+	})
+	if err := q.Drain(context.Background()); err != nil {
+		t.Errorf("Drain() error = %s", err)
+	}
+}
@@ -0,0 +1,233 @@
+package client
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	vocab "github.com/go-ap/activitypub"
+	"github.com/go-ap/errors"
+)
+
+// QueueItem is an activity waiting in the [Queue] to be delivered to a collection.
+type QueueItem struct {
+	ID string `json:"id"`
+	// Target is the IRI of the collection the activity gets posted to.
+	Target vocab.IRI `json:"target"`
+	// Activity is the JSON-LD document of the activity.
+	Activity json.RawMessage `json:"activity"`
+	// Created is the time when the item has been enqueued.
+	Created time.Time `json:"created"`
+	// Attempts is the number of failed delivery attempts.
+	Attempts int `json:"attempts"`
+	// Next is the time of the next delivery attempt.
+	Next time.Time `json:"next"`
+	// Status is the response status received for the last attempt, if it got one.
+	Status int `json:"status,omitempty"`
+	// Error is the error of the last attempt.
+	Error string `json:"error,omitempty"`
+}
+
+// QueueStore is the storage for the items of a delivery [Queue].
+type QueueStore interface {
+	// Put saves the it QueueItem, replacing the one with the same ID if it exists.
+	Put(it QueueItem) error
+	// Due returns at most max items which need to be delivered before the now time, the oldest first.
+	Due(now time.Time, max int) ([]QueueItem, error)
+	// Delete removes the item with the id from the queue.
+	Delete(id string) error
+	// Dead moves the it QueueItem from the queue to the dead letters.
+	Dead(it QueueItem) error
+	// DeadLetters returns the items that couldn't be delivered.
+	DeadLetters() ([]QueueItem, error)
+}
+
+type memQueueStore struct {
+	mu      sync.Mutex
+	pending map[string]QueueItem
+	dead    []QueueItem
+}
+
+// MemQueueStore returns a QueueStore which keeps the items in memory.
+func MemQueueStore() QueueStore {
+	return &memQueueStore{pending: make(map[string]QueueItem)}
+}
+
+func (s *memQueueStore) Put(it QueueItem) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pending[it.ID] = it
+	return nil
+}
+
+func (s *memQueueStore) Due(now time.Time, max int) ([]QueueItem, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	due := make([]QueueItem, 0)
+	for _, it := range s.pending {
+		if !it.Next.After(now) {
+			due = append(due, it)
+		}
+	}
+	return oldestFirst(due, max), nil
+}
+
+func (s *memQueueStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.pending, id)
+	return nil
+}
+
+func (s *memQueueStore) Dead(it QueueItem) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.pending, it.ID)
+	s.dead = append(s.dead, it)
+	return nil
+}
+
+func (s *memQueueStore) DeadLetters() ([]QueueItem, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.dead), nil
+}
+
+const (
+	queuePendingDir = "pending"
+	queueDeadDir    = "dead"
+)
+
+type fileQueueStore struct {
+	mu  sync.Mutex
+	dir string
+}
+
+// FileQueueStore returns a QueueStore which keeps every item in a JSON file under the dir directory,
+// so the items survive the restarts of the application.
+func FileQueueStore(dir string) (QueueStore, error) {
+	for _, sub := range []string{queuePendingDir, queueDeadDir} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o700); err != nil {
+			return nil, errors.Annotatef(err, "unable to create queue directory")
+		}
+	}
+	return &fileQueueStore{dir: dir}, nil
+}
+
+func (s *fileQueueStore) path(sub, id string) string {
+	return filepath.Join(s.dir, sub, filepath.Base(id)+".json")
+}
+
+// write saves the it item atomically, so a crash doesn't leave behind a partially written file.
+func (s *fileQueueStore) write(sub string, it QueueItem) error {
+	raw, err := json.Marshal(it)
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Join(s.dir, sub), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(raw); err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), s.path(sub, it.ID))
+}
+
+func (s *fileQueueStore) read(sub string) ([]QueueItem, error) {
+	entries, err := os.ReadDir(filepath.Join(s.dir, sub))
+	if err != nil {
+		return nil, err
+	}
+	items := make([]QueueItem, 0, len(entries))
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		raw, err := os.ReadFile(filepath.Join(s.dir, sub, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		it := QueueItem{}
+		if err = json.Unmarshal(raw, &it); err != nil {
+			return nil, errors.Annotatef(err, "invalid queue item %s", e.Name())
+		}
+		items = append(items, it)
+	}
+	return items, nil
+}
+
+func (s *fileQueueStore) Put(it QueueItem) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.write(queuePendingDir, it)
+}
+
+func (s *fileQueueStore) Due(now time.Time, max int) ([]QueueItem, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	items, err := s.read(queuePendingDir)
+	if err != nil {
+		return nil, err
+	}
+	items = slices.DeleteFunc(items, func(it QueueItem) bool {
+		return it.Next.After(now)
+	})
+	return oldestFirst(items, max), nil
+}
+
+func (s *fileQueueStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.Remove(s.path(queuePendingDir, id)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (s *fileQueueStore) Dead(it QueueItem) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.write(queueDeadDir, it); err != nil {
+		return err
+	}
+	if err := os.Remove(s.path(queuePendingDir, it.ID)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (s *fileQueueStore) DeadLetters() ([]QueueItem, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	items, err := s.read(queueDeadDir)
+	if err != nil {
+		return nil, err
+	}
+	return oldestFirst(items, 0), nil
+}
+
+// oldestFirst sorts the items by their next attempt, and it returns at most max of them, if max is larger than zero.
+func oldestFirst(items []QueueItem, max int) []QueueItem {
+	slices.SortFunc(items, func(a, b QueueItem) int {
+		if c := a.Next.Compare(b.Next); c != 0 {
+			return c
+		}
+		return a.Created.Compare(b.Created)
+	})
+	if max > 0 && len(items) > max {
+		items = items[:max]
+	}
+	return items
+}
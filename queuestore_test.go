@@ -0,0 +1,92 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestQueueStore(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	first := QueueItem{ID: "1", Target: "http://example.com/inbox", Activity: []byte(`{"type":"Like"}`), Created: now, Next: now.Add(-time.Minute)}
+	second := QueueItem{ID: "2", Target: "http://example.com/inbox", Activity: []byte(`{"type":"Like"}`), Created: now, Next: now}
+	later := QueueItem{ID: "3", Target: "http://example.com/inbox", Activity: []byte(`{"type":"Like"}`), Created: now, Next: now.Add(time.Hour)}
+
+	fileStore, err := FileQueueStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("FileQueueStore() error = %s", err)
+	}
+	stores := map[string]QueueStore{
+		"memory": MemQueueStore(),
+		"file":   fileStore,
+	}
+	for name, s := range stores {
+		t.Run(name, func(t *testing.T) {
+			for _, it := range []QueueItem{later, second, first} {
+				if err := s.Put(it); err != nil {
+					t.Fatalf("Put() error = %s", err)
+				}
+			}
+			got, err := s.Due(now, 0)
+			if err != nil {
+				t.Fatalf("Due() error = %s", err)
+			}
+			if want := []QueueItem{first, second}; !cmp.Equal(got, want) {
+				t.Errorf("Due() = %s", cmp.Diff(want, got))
+			}
+			if got, _ = s.Due(now, 1); !cmp.Equal(got, []QueueItem{first}) {
+				t.Errorf("Due() with max = %s", cmp.Diff([]QueueItem{first}, got))
+			}
+
+			updated := first
+			updated.Attempts = 1
+			updated.Next = now.Add(2 * time.Hour)
+			if err = s.Put(updated); err != nil {
+				t.Fatalf("Put() error = %s", err)
+			}
+			if err = s.Delete(second.ID); err != nil {
+				t.Fatalf("Delete() error = %s", err)
+			}
+			if err = s.Dead(later); err != nil {
+				t.Fatalf("Dead() error = %s", err)
+			}
+			if got, _ = s.Due(now.Add(3*time.Hour), 0); !cmp.Equal(got, []QueueItem{updated}) {
+				t.Errorf("Due() = %s", cmp.Diff([]QueueItem{updated}, got))
+			}
+			dead, err := s.DeadLetters()
+			if err != nil {
+				t.Fatalf("DeadLetters() error = %s", err)
+			}
+			if !cmp.Equal(dead, []QueueItem{later}) {
+				t.Errorf("DeadLetters() = %s", cmp.Diff([]QueueItem{later}, dead))
+			}
+		})
+	}
+}
+
+func TestFileQueueStore_persistence(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	it := QueueItem{ID: "1", Target: "http://example.com/inbox", Activity: []byte(`{"type":"Like"}`), Created: now, Next: now}
+
+	s, err := FileQueueStore(dir)
+	if err != nil {
+		t.Fatalf("FileQueueStore() error = %s", err)
+	}
+	if err = s.Put(it); err != nil {
+		t.Fatalf("Put() error = %s", err)
+	}
+
+	s, err = FileQueueStore(dir)
+	if err != nil {
+		t.Fatalf("FileQueueStore() error = %s", err)
+	}
+	got, err := s.Due(now, 0)
+	if err != nil {
+		t.Fatalf("Due() error = %s", err)
+	}
+	if !cmp.Equal(got, []QueueItem{it}) {
+		t.Errorf("Due() after reopening = %s", cmp.Diff([]QueueItem{it}, got))
+	}
+}
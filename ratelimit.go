@@ -0,0 +1,176 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimit is the configuration of the token bucket which limits the requests to a host.
+type RateLimit struct {
+	// Rate is the number of requests per second that can be sent on average. Zero means no limit.
+	Rate float64
+	// Burst is the maximum number of requests that can be sent at once.
+	Burst int
+}
+
+// RateLimitedError is returned when a request can't be sent to the Host before the deadline of its context,
+// because of the rate limits.
+type RateLimitedError struct {
+	Host string
+	// Wait is the duration until the request could have been sent.
+	Wait time.Duration
+}
+
+func (e RateLimitedError) Error() string {
+	return fmt.Sprintf("rate limit reached for %s, next request possible in %s", e.Host, e.Wait)
+}
+
+// WithRateLimit limits the requests that the client sends to every host, to the def [RateLimit],
+// or to the one in the overrides for the hosts which have one.
+// The limits are also lowered when the servers respond with X-RateLimit-Remaining and X-RateLimit-Reset headers.
+//
+// Requests wait for their turn, unless their context has a deadline which is earlier than that, in which case they
+// fail immediately with a [RateLimitedError].
+func WithRateLimit(def RateLimit, overrides map[string]RateLimit) OptionFn {
+	return func(c *C) {
+		c.rateLimit = newRateLimiter(def, overrides)
+	}
+}
+
+type bucket struct {
+	limit  RateLimit
+	tokens float64
+	last   time.Time
+	// blockedUntil is the reset time received from the server, after it said we ran out of requests.
+	blockedUntil time.Time
+}
+
+// refill adds the tokens accumulated since the last refill.
+func (b *bucket) refill(now time.Time) {
+	if b.limit.Rate <= 0 {
+		return
+	}
+	b.tokens = min(b.tokens+now.Sub(b.last).Seconds()*b.limit.Rate, float64(max(b.limit.Burst, 1)))
+	b.last = now
+}
+
+// reserve takes a token from the bucket, and it returns zero, or it returns how long to wait for one.
+func (b *bucket) reserve(now time.Time) time.Duration {
+	if now.Before(b.blockedUntil) {
+		return b.blockedUntil.Sub(now)
+	}
+	if b.limit.Rate <= 0 {
+		return 0
+	}
+	b.refill(now)
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / b.limit.Rate * float64(time.Second))
+}
+
+// rateLimiter keeps a token bucket for every host.
+// A nil rateLimiter doesn't impose any limits.
+type rateLimiter struct {
+	mu        sync.Mutex
+	def       RateLimit
+	overrides map[string]RateLimit
+	buckets   map[string]*bucket
+}
+
+func newRateLimiter(def RateLimit, overrides map[string]RateLimit) *rateLimiter {
+	r := rateLimiter{def: def, overrides: make(map[string]RateLimit), buckets: make(map[string]*bucket)}
+	for host, l := range overrides {
+		r.overrides[strings.ToLower(host)] = l
+	}
+	return &r
+}
+
+func (r *rateLimiter) bucket(host string, now time.Time) *bucket {
+	host = strings.ToLower(host)
+	b, ok := r.buckets[host]
+	if !ok {
+		l, ok := r.overrides[host]
+		if !ok {
+			l = r.def
+		}
+		b = &bucket{limit: l, tokens: float64(max(l.Burst, 1)), last: now}
+		r.buckets[host] = b
+	}
+	return b
+}
+
+// wait blocks until a request can be sent to the host, or it fails if that can't happen before the ctx deadline.
+func (r *rateLimiter) wait(ctx context.Context, host string) error {
+	if r == nil {
+		return nil
+	}
+	for {
+		now := time.Now()
+		r.mu.Lock()
+		d := r.bucket(host, now).reserve(now)
+		r.mu.Unlock()
+		if d <= 0 {
+			return nil
+		}
+		if deadline, ok := ctx.Deadline(); ok && now.Add(d).After(deadline) {
+			return RateLimitedError{Host: host, Wait: d}
+		}
+		if err := sleep(ctx, d); err != nil {
+			return err
+		}
+	}
+}
+
+// observe adapts the limits of the host to the X-RateLimit-Remaining and X-RateLimit-Reset headers of the res.
+func (r *rateLimiter) observe(host string, res *http.Response) {
+	if r == nil || res == nil {
+		return
+	}
+	remaining, err := strconv.ParseFloat(res.Header.Get("X-RateLimit-Remaining"), 64)
+	if err != nil {
+		return
+	}
+	now := time.Now()
+	reset, ok := parseRateLimitReset(res.Header.Get("X-RateLimit-Reset"), now)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	b := r.bucket(host, now)
+	if remaining < 1 && ok {
+		b.blockedUntil = reset
+		return
+	}
+	if b.limit.Rate > 0 {
+		b.refill(now)
+		b.tokens = min(b.tokens, remaining)
+	}
+}
+
+// parseRateLimitReset parses the X-RateLimit-Reset header value, which can be a timestamp, like Mastodon sends,
+// a UNIX epoch, or a number of seconds.
+func parseRateLimitReset(val string, now time.Time) (time.Time, bool) {
+	val = strings.TrimSpace(val)
+	if len(val) == 0 {
+		return time.Time{}, false
+	}
+	if t, err := time.Parse(time.RFC3339Nano, val); err == nil {
+		return t, true
+	}
+	sec, err := strconv.ParseFloat(val, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	// NOTE(marius): values that are too large to be a number of seconds must be UNIX epochs
+	if sec > 1e9 {
+		return time.Unix(0, int64(sec*float64(time.Second))), true
+	}
+	return now.Add(time.Duration(sec * float64(time.Second))), true
+}
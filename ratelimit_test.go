@@ -0,0 +1,102 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"git.sr.ht/~mariusor/lw"
+	"github.com/go-ap/errors"
+)
+
+func Test_parseRateLimitReset(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		val    string
+		want   time.Time
+		wantOk bool
+	}{
+		{val: ""},
+		{val: "soon"},
+		{val: "2024-01-01T00:05:00.000Z", want: now.Add(5 * time.Minute), wantOk: true},
+		{val: "1704067500", want: now.Add(5 * time.Minute), wantOk: true},
+		{val: "300", want: now.Add(5 * time.Minute), wantOk: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.val, func(t *testing.T) {
+			got, ok := parseRateLimitReset(tt.val, now)
+			if !got.Equal(tt.want) || ok != tt.wantOk {
+				t.Errorf("parseRateLimitReset() = %s, %t, want %s, %t", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func Test_rateLimiter_wait(t *testing.T) {
+	r := newRateLimiter(RateLimit{Rate: 100, Burst: 2}, map[string]RateLimit{"slow.example.com": {Rate: 1, Burst: 1}})
+
+	st := time.Now()
+	for range 4 {
+		if err := r.wait(context.Background(), "example.com"); err != nil {
+			t.Fatalf("wait() error = %s", err)
+		}
+	}
+	// NOTE(marius): the first two requests use the burst, the next two wait 10ms each
+	if elapsed := time.Since(st); elapsed < 15*time.Millisecond {
+		t.Errorf("wait() for 4 requests took %s, expected about 20ms", elapsed)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := r.wait(ctx, "slow.example.com"); err != nil {
+		t.Fatalf("wait() error = %s", err)
+	}
+	err := r.wait(ctx, "SLOW.example.com")
+	rle := RateLimitedError{}
+	if !errors.As(err, &rle) {
+		t.Fatalf("wait() error = %v, expected a RateLimitedError", err)
+	}
+	if rle.Host != "SLOW.example.com" || rle.Wait < 900*time.Millisecond {
+		t.Errorf("wait() error = %#v", rle)
+	}
+
+	var nilLimiter *rateLimiter
+	if err = nilLimiter.wait(ctx, "example.com"); err != nil {
+		t.Errorf("wait() on nil limiter error = %s", err)
+	}
+}
+
+func TestC_Do_withRateLimit(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("X-RateLimit-Limit", "300")
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Header().Set("X-RateLimit-Reset", time.Now().Add(time.Hour).UTC().Format(time.RFC3339Nano))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	c := C{c: srv.Client(), l: lw.Dev(lw.SetOutput(t.Output()))}
+	WithRateLimit(RateLimit{}, nil)(&c)
+
+	if _, err := c.Do(mockGetReq()); err != nil {
+		t.Fatalf("Do() error = %s", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_, err := c.Do(mockGetReq().WithContext(ctx))
+	rle := RateLimitedError{}
+	if !errors.As(err, &rle) {
+		t.Fatalf("Do() error = %v, expected a RateLimitedError", err)
+	}
+	if rle.Wait < 59*time.Minute {
+		t.Errorf("Do() error = %s, expected to wait for the reset", rle)
+	}
+	if calls != 1 {
+		t.Errorf("Do() calls = %d, the second request should not have been sent", calls)
+	}
+}
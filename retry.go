@@ -0,0 +1,166 @@
+package client
+
+import (
+	"context"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-ap/errors"
+)
+
+// RetryPolicy configures how the client retries the requests that failed with transient errors.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of times that a request is sent, including the first one.
+	MaxAttempts int
+	// BaseDelay is the delay before the first retry, which gets doubled for every subsequent one.
+	BaseDelay time.Duration
+	// MaxDelay caps the delay between attempts, including the ones requested by the servers through Retry-After.
+	// If it's zero, the doubling of the BaseDelay is capped at 30 seconds, and Retry-After is not capped.
+	MaxDelay time.Duration
+	// Statuses are the response statuses for which requests get retried.
+	// If it's empty, the [DefaultRetryStatuses] are used.
+	Statuses []int
+}
+
+// DefaultRetryStatuses are the response statuses that the [RetryPolicy] retries by default.
+var DefaultRetryStatuses = []int{
+	http.StatusTooManyRequests,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// defaultMaxRetryDelay caps the delays of the RetryPolicy values that don't have a MaxDelay.
+const defaultMaxRetryDelay = 30 * time.Second
+
+// DefaultRetryPolicy is a RetryPolicy suitable for most uses.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   500 * time.Millisecond,
+	MaxDelay:    defaultMaxRetryDelay,
+}
+
+// WithRetryPolicy makes the client retry the requests that failed with transient errors, as configured by the p [RetryPolicy].
+// Every attempt goes through all the authorization functions of the client, as a request without retries would.
+func WithRetryPolicy(p RetryPolicy) OptionFn {
+	return func(c *C) {
+		c.retry = &p
+	}
+}
+
+// sleep waits for the d duration, or until the ctx is done.
+var sleep = func(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func isIdempotent(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead
+}
+
+// shouldRetry returns true if the request can be retried after receiving res and err.
+func (p RetryPolicy) shouldRetry(req *http.Request, res *http.Response, err error) bool {
+	if err != nil {
+		if errors.As(err, &CircuitOpenError{}) {
+			return false
+		}
+		// NOTE(marius): we don't know if the server has acted on non-idempotent requests
+		return isIdempotent(req.Method) && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+	statuses := p.Statuses
+	if len(statuses) == 0 {
+		statuses = DefaultRetryStatuses
+	}
+	if !slices.Contains(statuses, res.StatusCode) {
+		return false
+	}
+	// NOTE(marius): only 429 and 503 guarantee that the server didn't process the request
+	return isIdempotent(req.Method) || res.StatusCode == http.StatusTooManyRequests || res.StatusCode == http.StatusServiceUnavailable
+}
+
+// delay returns the duration to wait before the attempt numbered try, which is larger than zero.
+func (p RetryPolicy) delay(try int, res *http.Response) time.Duration {
+	d, ok := retryAfter(res)
+	if !ok {
+		limit := p.MaxDelay
+		if limit <= 0 {
+			limit = defaultMaxRetryDelay
+		}
+		// NOTE(marius): we compare with the limit before shifting, so the doubling can't overflow
+		d = limit
+		if shift := max(try-1, 0); shift < 63 && p.BaseDelay > 0 && p.BaseDelay <= limit>>shift {
+			d = p.BaseDelay << shift
+		}
+		// NOTE(marius): we add jitter so the clients that failed at the same time don't retry at the same time.
+		if half := int64(d / 2); half > 0 {
+			d = time.Duration(half + rand.Int64N(half+1))
+		}
+		return d
+	}
+	if p.MaxDelay > 0 {
+		d = min(d, p.MaxDelay)
+	}
+	return d
+}
+
+// retryAfter parses the Retry-After header of the res, in either its seconds or its HTTP-date form.
+//
+// https://www.rfc-editor.org/rfc/rfc9110#section-10.2.3
+func retryAfter(res *http.Response) (time.Duration, bool) {
+	if res == nil {
+		return 0, false
+	}
+	val := strings.TrimSpace(res.Header.Get("Retry-After"))
+	if len(val) == 0 {
+		return 0, false
+	}
+	if sec, err := strconv.Atoi(val); err == nil {
+		return max(time.Duration(sec)*time.Second, 0), true
+	}
+	if when, err := http.ParseTime(val); err == nil {
+		return max(when.Sub(TimeNow()), 0), true
+	}
+	return 0, false
+}
+
+func (c C) doWithRetries(req *http.Request) (res *http.Response, err error) {
+	p := c.retry
+	attempts := max(p.MaxAttempts, 1)
+	for try := 0; try < attempts; try++ {
+		if try > 0 {
+			d := p.delay(try, res)
+			if res != nil {
+				_, _ = io.Copy(io.Discard, res.Body)
+				_ = res.Body.Close()
+			}
+			lc := Ctx{"host": req.URL.Hostname(), "attempt": try + 1, "delay": d}
+			if res != nil {
+				lc["status"] = res.StatusCode
+			}
+			if err != nil {
+				lc["err"] = err.Error()
+			}
+			c.l.WithContext(lc).Warnf("retrying request")
+			if serr := sleep(req.Context(), d); serr != nil {
+				return nil, serr
+			}
+		}
+
+		res, err = c.do(cloneRequest(req, try == attempts-1))
+		if try == attempts-1 || !p.shouldRetry(req, res, err) {
+			break
+		}
+	}
+	return res, err
+}
@@ -0,0 +1,241 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"git.sr.ht/~mariusor/lw"
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_retryAfter(t *testing.T) {
+	TimeNow = mockTimeFn
+
+	tests := []struct {
+		name   string
+		header string
+		want   time.Duration
+		wantOk bool
+	}{
+		{
+			name: "missing",
+		},
+		{
+			name:   "seconds",
+			header: "120",
+			want:   2 * time.Minute,
+			wantOk: true,
+		},
+		{
+			name:   "HTTP date",
+			header: mockTimeFn().Add(90 * time.Second).Format(http.TimeFormat),
+			want:   90 * time.Second,
+			wantOk: true,
+		},
+		{
+			name:   "HTTP date in the past",
+			header: mockTimeFn().Add(-time.Hour).Format(http.TimeFormat),
+			want:   0,
+			wantOk: true,
+		},
+		{
+			name:   "invalid",
+			header: "soon",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := &http.Response{Header: http.Header{}}
+			if len(tt.header) > 0 {
+				res.Header.Set("Retry-After", tt.header)
+			}
+			got, ok := retryAfter(res)
+			if got != tt.want || ok != tt.wantOk {
+				t.Errorf("retryAfter() = %s, %t, want %s, %t", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestRetryPolicy_delay(t *testing.T) {
+	p := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	tests := []struct {
+		try     int
+		res     *http.Response
+		wantMin time.Duration
+		wantMax time.Duration
+	}{
+		{try: 1, wantMin: 50 * time.Millisecond, wantMax: 100 * time.Millisecond},
+		{try: 2, wantMin: 100 * time.Millisecond, wantMax: 200 * time.Millisecond},
+		{try: 3, wantMin: 200 * time.Millisecond, wantMax: 400 * time.Millisecond},
+		{try: 10, wantMin: 500 * time.Millisecond, wantMax: time.Second},
+		{try: 100, wantMin: 500 * time.Millisecond, wantMax: time.Second},
+		{
+			try:     1,
+			res:     &http.Response{Header: http.Header{"Retry-After": []string{"3600"}}},
+			wantMin: time.Second,
+			wantMax: time.Second,
+		},
+	}
+	for _, tt := range tests {
+		for range 10 {
+			if got := p.delay(tt.try, tt.res); got < tt.wantMin || got > tt.wantMax {
+				t.Errorf("delay(%d) = %s, want between %s and %s", tt.try, got, tt.wantMin, tt.wantMax)
+			}
+		}
+	}
+
+	// NOTE(marius): without a MaxDelay, the shift must neither wrap around, nor grow without bounds
+	unbounded := RetryPolicy{BaseDelay: 3 * time.Second}
+	for _, try := range []int{5, 33, 34, 62, 63, 64, 100} {
+		if got := unbounded.delay(try, nil); got < defaultMaxRetryDelay/2 || got > defaultMaxRetryDelay {
+			t.Errorf("delay(%d) without MaxDelay = %s, want between %s and %s", try, got, defaultMaxRetryDelay/2, defaultMaxRetryDelay)
+		}
+	}
+}
+
+func TestC_Do_withRetryPolicy(t *testing.T) {
+	TimeNow = mockTimeFn
+	defer func(fn func(context.Context, time.Duration) error) {
+		sleep = fn
+	}(sleep)
+
+	type response struct {
+		status     int
+		retryAfter string
+		hangUp     bool
+	}
+	tests := []struct {
+		name       string
+		policy     RetryPolicy
+		authFns    []func(*http.Request) error
+		req        *http.Request
+		responses  []response
+		wantStatus int
+		wantErr    bool
+		wantCalls  int
+		wantDelays []time.Duration
+	}{
+		{
+			name:       "429 with Retry-After",
+			policy:     RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Minute},
+			req:        mockGetReq(),
+			responses:  []response{{status: http.StatusTooManyRequests, retryAfter: "5"}, {status: http.StatusOK}},
+			wantStatus: http.StatusOK,
+			wantCalls:  2,
+			wantDelays: []time.Duration{5 * time.Second},
+		},
+		{
+			name:       "503 with Retry-After date",
+			policy:     RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Minute},
+			req:        mockGetReq(),
+			responses:  []response{{status: http.StatusServiceUnavailable, retryAfter: mockTimeFn().Add(10 * time.Second).Format(http.TimeFormat)}, {status: http.StatusOK}},
+			wantStatus: http.StatusOK,
+			wantCalls:  2,
+			wantDelays: []time.Duration{10 * time.Second},
+		},
+		{
+			name:       "max attempts",
+			policy:     RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Second},
+			req:        mockGetReq(),
+			responses:  []response{{status: http.StatusBadGateway}, {status: http.StatusTooManyRequests, retryAfter: "60"}, {status: http.StatusOK}},
+			wantStatus: http.StatusTooManyRequests,
+			wantCalls:  2,
+		},
+		{
+			name:       "POST is retried on 503",
+			policy:     RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond},
+			req:        mockPostReq([]byte("{}")),
+			responses:  []response{{status: http.StatusServiceUnavailable}, {status: http.StatusCreated}},
+			wantStatus: http.StatusCreated,
+			wantCalls:  2,
+		},
+		{
+			name:       "POST is not retried on 502",
+			policy:     RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond},
+			req:        mockPostReq([]byte("{}")),
+			responses:  []response{{status: http.StatusBadGateway}, {status: http.StatusCreated}},
+			wantStatus: http.StatusBadGateway,
+			wantCalls:  1,
+		},
+		{
+			name:       "network errors are retried for GET",
+			policy:     RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond},
+			req:        mockGetReq(),
+			responses:  []response{{hangUp: true}, {status: http.StatusOK}},
+			wantStatus: http.StatusOK,
+			wantCalls:  2,
+		},
+		{
+			name:      "network errors are not retried for POST",
+			policy:    RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond},
+			req:       mockPostReq([]byte("{}")),
+			responses: []response{{hangUp: true}, {status: http.StatusOK}},
+			wantErr:   true,
+			wantCalls: 1,
+		},
+		{
+			name:   "every attempt rotates the authorization functions",
+			policy: RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond},
+			authFns: []func(*http.Request) error{
+				mockAuthFn("first"),
+				mockAuthFn("second"),
+			},
+			req:        mockPostReq([]byte("{}")),
+			responses:  []response{{status: http.StatusUnauthorized}, {status: http.StatusTooManyRequests}, {status: http.StatusUnauthorized}, {status: http.StatusCreated}},
+			wantStatus: http.StatusCreated,
+			wantCalls:  4,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotDelays []time.Duration
+			sleep = func(_ context.Context, d time.Duration) error {
+				gotDelays = append(gotDelays, d)
+				return nil
+			}
+
+			calls := atomic.Int32{}
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				if r.Method == http.MethodPost && !bytes.Equal(body, []byte("{}")) {
+					t.Errorf("invalid request body %q", body)
+				}
+				res := tt.responses[min(int(calls.Add(1)), len(tt.responses))-1]
+				if res.hangUp {
+					conn, _, _ := w.(http.Hijacker).Hijack()
+					_ = conn.Close()
+					return
+				}
+				if len(res.retryAfter) > 0 {
+					w.Header().Set("Retry-After", res.retryAfter)
+				}
+				w.WriteHeader(res.status)
+			}))
+			defer srv.Close()
+
+			c := C{c: srv.Client(), l: lw.Dev(lw.SetOutput(t.Output())), authFns: tt.authFns}
+			WithRetryPolicy(tt.policy)(&c)
+
+			got, err := c.Do(tt.req)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Do() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if got != nil && got.StatusCode != tt.wantStatus {
+				t.Errorf("Do() status = %d, want %d", got.StatusCode, tt.wantStatus)
+			}
+			if n := int(calls.Load()); n != tt.wantCalls {
+				t.Errorf("Do() calls = %d, want %d", n, tt.wantCalls)
+			}
+			if tt.wantDelays != nil && !cmp.Equal(gotDelays, tt.wantDelays) {
+				t.Errorf("Do() delays = %s", cmp.Diff(tt.wantDelays, gotDelays))
+			}
+		})
+	}
+}
@@ -0,0 +1,238 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"iter"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	vocab "github.com/go-ap/activitypub"
+	"github.com/go-ap/errors"
+	"github.com/go-ap/filters"
+)
+
+// checkpointSize is the number of item IDs we store in a Cursor.
+// We keep more than one, so we can still find the checkpoint if the newest items get deleted.
+const checkpointSize = 10
+
+// Cursor is the checkpoint of the last synchronization of a collection.
+type Cursor struct {
+	// Newest contains the IDs of the most recent items of the collection at the time of the last synchronization.
+	Newest vocab.IRIs `json:"newest,omitempty"`
+	// Page is the IRI of the collection page where an interrupted synchronization stopped, and where
+	// the next one resumes from.
+	Page vocab.IRI `json:"page,omitempty"`
+	// Pending contains the IDs of the most recent items returned by the interrupted synchronization,
+	// which replace the Newest ones when the resumed synchronization reaches them.
+	Pending vocab.IRIs `json:"pending,omitempty"`
+	// Updated is the time when the Cursor has been saved.
+	Updated time.Time `json:"updated"`
+}
+
+// CursorStore is the storage for the Cursor values of synchronized collections.
+type CursorStore interface {
+	// Load returns the Cursor saved for the collection iri [vocab.IRI], or an empty one if nothing has been saved yet.
+	Load(iri vocab.IRI) (Cursor, error)
+	// Save stores the cur Cursor for the collection iri [vocab.IRI].
+	Save(iri vocab.IRI, cur Cursor) error
+}
+
+// Sync returns an iterator over the items of the collection found at the iri [vocab.IRI], which have been
+// added since the previous synchronization recorded in the store [CursorStore].
+//
+// It expects the collection to be sorted newest first, as the ActivityPub specification requires for inboxes
+// and outboxes, and it stops walking the collection when it reaches one of the items in the stored Cursor.
+// If the iteration is interrupted, or it fails, the page where it stopped is saved in the Cursor, and the next call
+// first resumes the walk from that page, which means that the items of that page can be returned again.
+// It then walks the collection from the beginning again, for the items that have been added in the meantime.
+func (c C) Sync(ctx context.Context, store CursorStore, iri vocab.IRI, ff ...filters.Check) iter.Seq2[vocab.Item, error] {
+	iri = irif(iri, ff...)
+	return func(yield func(vocab.Item, error) bool) {
+		if store == nil {
+			yield(nil, errors.Newf("invalid nil cursor store"))
+			return
+		}
+		prev, err := store.Load(iri)
+		if err != nil {
+			yield(nil, errors.Annotatef(err, "unable to load cursor for %s", iri))
+			return
+		}
+
+		checkpoint := prev.Newest
+		if len(prev.Page) > 0 {
+			newest, page, err := c.syncWalk(ctx, prev.Page, checkpoint, prev.Pending, yield, ff...)
+			switch {
+			case err != nil && page == prev.Page:
+				// NOTE(marius): the page we stopped at can't be loaded anymore, so we walk the whole collection again
+				c.l.WithContext(Ctx{"iri": iri, "page": page, "err": err.Error()}).Warnf("unable to resume the synchronization")
+			case len(page) > 0:
+				c.syncInterrupted(store, iri, Cursor{Newest: checkpoint, Page: page, Pending: newest}, err, yield)
+				return
+			default:
+				checkpoint = mergeCheckpoint(newest, checkpoint)
+			}
+		}
+
+		newest, page, err := c.syncWalk(ctx, iri, checkpoint, nil, yield, ff...)
+		if len(page) > 0 {
+			c.syncInterrupted(store, iri, Cursor{Newest: checkpoint, Page: page, Pending: newest}, err, yield)
+			return
+		}
+		cur := Cursor{Newest: mergeCheckpoint(newest, checkpoint), Updated: TimeNow()}
+		if err := store.Save(iri, cur); err != nil {
+			yield(nil, errors.Annotatef(err, "unable to save cursor for %s", iri))
+		}
+	}
+}
+
+// syncWalk yields the items of the collection pages, starting at the start IRI, until it reaches one of the stop items.
+// It returns the IDs of the newest yielded items, after the ones in newest, and if the walk doesn't reach
+// the end, the page where it stopped, with the error that stopped it, if any.
+func (c C) syncWalk(ctx context.Context, start vocab.IRI, stop, newest vocab.IRIs, yield func(vocab.Item, error) bool, ff ...filters.Check) (vocab.IRIs, vocab.IRI, error) {
+	newest = slices.Clone(newest)
+	for p, err := range c.pages(ctx, start, Forward, ff...) {
+		if err != nil {
+			return newest, p.IRI, err
+		}
+		for _, it := range p.Items {
+			if stop.Contains(it) {
+				return newest, "", nil
+			}
+			if id := it.GetLink(); len(id) > 0 && len(newest) < checkpointSize && !newest.Contains(id) {
+				newest = append(newest, id)
+			}
+			if !yield(it, nil) {
+				return newest, p.IRI, nil
+			}
+		}
+	}
+	return newest, "", nil
+}
+
+// syncInterrupted saves the cur Cursor of an interrupted synchronization, and it yields the err error that caused it.
+func (c C) syncInterrupted(store CursorStore, iri vocab.IRI, cur Cursor, err error, yield func(vocab.Item, error) bool) {
+	cur.Updated = TimeNow()
+	if serr := store.Save(iri, cur); serr != nil {
+		c.l.WithContext(Ctx{"iri": iri, "err": serr.Error()}).Warnf("unable to save cursor")
+	}
+	if err != nil {
+		yield(nil, err)
+	}
+}
+
+// mergeCheckpoint returns the newest IDs, followed by the ones of the prev checkpoint, up to the checkpointSize.
+func mergeCheckpoint(newest, prev vocab.IRIs) vocab.IRIs {
+	if len(newest) == 0 {
+		// NOTE(marius): nothing new, we keep the old checkpoint
+		return prev
+	}
+	cur := slices.Clone(newest)
+	for _, id := range prev {
+		if len(cur) == checkpointSize {
+			break
+		}
+		if !cur.Contains(id) {
+			cur = append(cur, id)
+		}
+	}
+	return cur
+}
+
+type memCursors struct {
+	mu sync.RWMutex
+	m  map[vocab.IRI]Cursor
+}
+
+// MemCursors returns a CursorStore which keeps the cursors in memory.
+func MemCursors() CursorStore {
+	return &memCursors{m: make(map[vocab.IRI]Cursor)}
+}
+
+func (s *memCursors) Load(iri vocab.IRI) (Cursor, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.m[iri], nil
+}
+
+func (s *memCursors) Save(iri vocab.IRI, cur Cursor) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.m[iri] = cur
+	return nil
+}
+
+type fileCursors struct {
+	mu   sync.Mutex
+	path string
+}
+
+// FileCursors returns a CursorStore which keeps the cursors in a JSON document at the path location.
+// The file is created at the first Save.
+func FileCursors(path string) CursorStore {
+	return &fileCursors{path: path}
+}
+
+func (s *fileCursors) read() (map[vocab.IRI]Cursor, error) {
+	m := make(map[vocab.IRI]Cursor)
+	raw, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return m, nil
+		}
+		return nil, err
+	}
+	if len(raw) == 0 {
+		return m, nil
+	}
+	if err = json.Unmarshal(raw, &m); err != nil {
+		return nil, errors.Annotatef(err, "invalid cursors file %s", s.path)
+	}
+	return m, nil
+}
+
+func (s *fileCursors) Load(iri vocab.IRI) (Cursor, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m, err := s.read()
+	if err != nil {
+		return Cursor{}, err
+	}
+	return m[iri], nil
+}
+
+func (s *fileCursors) Save(iri vocab.IRI, cur Cursor) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m, err := s.read()
+	if err != nil {
+		return err
+	}
+	m[iri] = cur
+	raw, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	// NOTE(marius): we write to a temporary file first, so a failure doesn't leave a truncated document behind.
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.Remove(tmp.Name())
+	}()
+	if _, err = tmp.Write(raw); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
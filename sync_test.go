@@ -0,0 +1,195 @@
+package client
+
+import (
+	"context"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"git.sr.ht/~mariusor/lw"
+	vocab "github.com/go-ap/activitypub"
+	"github.com/google/go-cmp/cmp"
+)
+
+func mockOutbox(ids ...vocab.IRI) map[string]vocab.Item {
+	return map[string]vocab.Item{
+		"/outbox": &vocab.OrderedCollection{
+			ID:    "http://example.com/outbox",
+			Type:  vocab.OrderedCollectionType,
+			First: vocab.IRI("http://example.com/outbox?page=1"),
+		},
+		"/outbox?page=1": &vocab.OrderedCollectionPage{
+			ID:           "http://example.com/outbox?page=1",
+			Type:         vocab.OrderedCollectionPageType,
+			Next:         vocab.IRI("http://example.com/outbox?page=2"),
+			OrderedItems: mockItems(ids[:min(2, len(ids))]...),
+		},
+		"/outbox?page=2": &vocab.OrderedCollectionPage{
+			ID:           "http://example.com/outbox?page=2",
+			Type:         vocab.OrderedCollectionPageType,
+			OrderedItems: mockItems(ids[min(2, len(ids)):]...),
+		},
+	}
+}
+
+func TestC_Sync(t *testing.T) {
+	TimeNow = mockTimeFn
+
+	tests := []struct {
+		name       string
+		prev       Cursor
+		ids        vocab.IRIs
+		stopAfter  int
+		want       vocab.ItemCollection
+		wantCursor Cursor
+	}{
+		{
+			name:       "first sync",
+			ids:        vocab.IRIs{"http://example.com/3", "http://example.com/2", "http://example.com/1"},
+			want:       mockItems("http://example.com/3", "http://example.com/2", "http://example.com/1"),
+			wantCursor: Cursor{Newest: vocab.IRIs{"http://example.com/3", "http://example.com/2", "http://example.com/1"}, Updated: mockTimeFn()},
+		},
+		{
+			name:       "nothing new",
+			prev:       Cursor{Newest: vocab.IRIs{"http://example.com/3", "http://example.com/2", "http://example.com/1"}},
+			ids:        vocab.IRIs{"http://example.com/3", "http://example.com/2", "http://example.com/1"},
+			want:       nil,
+			wantCursor: Cursor{Newest: vocab.IRIs{"http://example.com/3", "http://example.com/2", "http://example.com/1"}, Updated: mockTimeFn()},
+		},
+		{
+			name:       "new items on multiple pages",
+			prev:       Cursor{Newest: vocab.IRIs{"http://example.com/2", "http://example.com/1"}},
+			ids:        vocab.IRIs{"http://example.com/5", "http://example.com/4", "http://example.com/3", "http://example.com/2", "http://example.com/1"},
+			want:       mockItems("http://example.com/5", "http://example.com/4", "http://example.com/3"),
+			wantCursor: Cursor{Newest: vocab.IRIs{"http://example.com/5", "http://example.com/4", "http://example.com/3", "http://example.com/2", "http://example.com/1"}, Updated: mockTimeFn()},
+		},
+		{
+			name:       "newest item has been deleted",
+			prev:       Cursor{Newest: vocab.IRIs{"http://example.com/3", "http://example.com/2"}},
+			ids:        vocab.IRIs{"http://example.com/4", "http://example.com/2", "http://example.com/1"},
+			want:       mockItems("http://example.com/4"),
+			wantCursor: Cursor{Newest: vocab.IRIs{"http://example.com/4", "http://example.com/3", "http://example.com/2"}, Updated: mockTimeFn()},
+		},
+		{
+			name:      "interrupted sync saves the page",
+			prev:      Cursor{Newest: vocab.IRIs{"http://example.com/1"}},
+			ids:       vocab.IRIs{"http://example.com/3", "http://example.com/2", "http://example.com/1"},
+			stopAfter: 1,
+			want:      mockItems("http://example.com/3"),
+			wantCursor: Cursor{
+				Newest:  vocab.IRIs{"http://example.com/1"},
+				Page:    "http://example.com/outbox?page=1",
+				Pending: vocab.IRIs{"http://example.com/3"},
+				Updated: mockTimeFn(),
+			},
+		},
+		{
+			name: "resumed sync",
+			prev: Cursor{
+				Newest:  vocab.IRIs{"http://example.com/1"},
+				Page:    "http://example.com/outbox?page=2",
+				Pending: vocab.IRIs{"http://example.com/5"},
+			},
+			ids:        vocab.IRIs{"http://example.com/6", "http://example.com/5", "http://example.com/4", "http://example.com/3", "http://example.com/1"},
+			want:       mockItems("http://example.com/4", "http://example.com/3", "http://example.com/6"),
+			wantCursor: Cursor{Newest: vocab.IRIs{"http://example.com/6", "http://example.com/5", "http://example.com/4", "http://example.com/3", "http://example.com/1"}, Updated: mockTimeFn()},
+		},
+		{
+			name: "interrupted resumed sync",
+			prev: Cursor{
+				Newest:  vocab.IRIs{"http://example.com/1"},
+				Page:    "http://example.com/outbox?page=2",
+				Pending: vocab.IRIs{"http://example.com/5"},
+			},
+			ids:       vocab.IRIs{"http://example.com/6", "http://example.com/5", "http://example.com/4", "http://example.com/3", "http://example.com/1"},
+			stopAfter: 1,
+			want:      mockItems("http://example.com/4"),
+			wantCursor: Cursor{
+				Newest:  vocab.IRIs{"http://example.com/1"},
+				Page:    "http://example.com/outbox?page=2",
+				Pending: vocab.IRIs{"http://example.com/5", "http://example.com/4"},
+				Updated: mockTimeFn(),
+			},
+		},
+		{
+			name: "resumed page doesn't exist anymore",
+			prev: Cursor{
+				Newest:  vocab.IRIs{"http://example.com/1"},
+				Page:    "http://example.com/outbox?page=9",
+				Pending: vocab.IRIs{"http://example.com/3"},
+			},
+			ids:        vocab.IRIs{"http://example.com/3", "http://example.com/2", "http://example.com/1"},
+			want:       mockItems("http://example.com/3", "http://example.com/2"),
+			wantCursor: Cursor{Newest: vocab.IRIs{"http://example.com/3", "http://example.com/2", "http://example.com/1"}, Updated: mockTimeFn()},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(mockPagesHandler(mockOutbox(tt.ids...)))
+			defer srv.Close()
+
+			c := C{
+				c: srv.Client(),
+				l: lw.Dev(lw.SetOutput(t.Output())),
+			}
+
+			iri := vocab.IRI("http://example.com/outbox")
+			store := MemCursors()
+			_ = store.Save(iri, tt.prev)
+
+			var got vocab.ItemCollection
+			for it, err := range c.Sync(context.Background(), store, iri) {
+				if err != nil {
+					t.Fatalf("Sync() error = %s", err)
+				}
+				got = append(got, it)
+				if tt.stopAfter > 0 && len(got) == tt.stopAfter {
+					break
+				}
+			}
+			if !cmp.Equal(got, tt.want, EquateItems) {
+				t.Errorf("Sync() got = %s", cmp.Diff(tt.want, got, EquateItems))
+			}
+			gotCursor, _ := store.Load(iri)
+			if !cmp.Equal(gotCursor, tt.wantCursor) {
+				t.Errorf("Sync() cursor = %s", cmp.Diff(tt.wantCursor, gotCursor))
+			}
+		})
+	}
+}
+
+func TestFileCursors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cursors.json")
+	store := FileCursors(path)
+
+	iri := vocab.IRI("http://example.com/outbox")
+	got, err := store.Load(iri)
+	if err != nil {
+		t.Fatalf("Load() on missing file error = %s", err)
+	}
+	if !cmp.Equal(got, Cursor{}) {
+		t.Errorf("Load() on missing file = %s", cmp.Diff(Cursor{}, got))
+	}
+
+	want := Cursor{
+		Newest:  vocab.IRIs{"http://example.com/2", "http://example.com/1"},
+		Page:    "http://example.com/outbox?page=2",
+		Pending: vocab.IRIs{"http://example.com/4"},
+		Updated: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	if err = store.Save(iri, want); err != nil {
+		t.Fatalf("Save() error = %s", err)
+	}
+	if err = store.Save("http://example.com/inbox", Cursor{}); err != nil {
+		t.Fatalf("Save() error = %s", err)
+	}
+
+	got, err = FileCursors(path).Load(iri)
+	if err != nil {
+		t.Fatalf("Load() error = %s", err)
+	}
+	if !cmp.Equal(got, want) {
+		t.Errorf("Load() = %s", cmp.Diff(want, got))
+	}
+}
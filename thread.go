@@ -0,0 +1,336 @@
+package client
+
+import (
+	"context"
+
+	vocab "github.com/go-ap/activitypub"
+	"github.com/go-ap/errors"
+)
+
+const (
+	defaultThreadAncestors = 20
+	defaultThreadDepth     = 20
+	defaultThreadSize      = 200
+)
+
+// WithThreadLimits sets the maximum number of ancestors, the maximum depth of the replies,
+// and the maximum number of objects of a conversation loaded by [C.Thread].
+func WithThreadLimits(ancestors, depth, size int) OptionFn {
+	return func(c *C) {
+		c.threadAncestors = ancestors
+		c.threadDepth = depth
+		c.threadSize = size
+	}
+}
+
+// WithConversationContext makes [C.Thread] load the collection found in the context property of the object,
+// when the server exposes one, instead of walking the replies collection of every object of the conversation.
+func WithConversationContext() OptionFn {
+	return func(c *C) {
+		c.threadContext = true
+	}
+}
+
+// ThreadNode is an object of a conversation, linked to the object it replies to and to its replies.
+type ThreadNode struct {
+	// IRI is the ID of the object.
+	IRI vocab.IRI
+	// Item is the loaded object. It is nil if the object could not be loaded.
+	Item vocab.Item
+	// Err is the error received when loading the object.
+	Err error
+	// Parent is the object this one replies to, nil for the root of the thread.
+	Parent *ThreadNode
+	// Replies are the replies to the object.
+	Replies []*ThreadNode
+}
+
+// Placeholder returns true if the object of the node has been deleted, or if it could not be loaded.
+func (n *ThreadNode) Placeholder() bool {
+	return n.Err != nil || vocab.IsNil(n.Item) || vocab.TombstoneType.Match(n.Item.GetType())
+}
+
+// Thread is the tree of a conversation.
+type Thread struct {
+	// Root is the top-most object of the conversation that could be reached.
+	Root *ThreadNode
+	// Node is the object the thread has been built for.
+	Node *ThreadNode
+	// Truncated is true if one of the limits has been reached, and objects are missing from the thread.
+	Truncated bool
+}
+
+// Thread builds the conversation tree of the object found at the iri [vocab.IRI].
+// It walks the inReplyTo properties up to the root of the conversation, and the replies collections
+// down from the object, in the limits set by [WithThreadLimits].
+//
+// Objects which are deleted, or which can't be loaded, are kept in the tree as placeholder nodes,
+// see [ThreadNode.Placeholder]. Only failing to load the object itself returns an error.
+func (c C) Thread(ctx context.Context, iri vocab.IRI) (*Thread, error) {
+	it, err := c.loadCtx(ctx, iri)
+	if err != nil {
+		return nil, errors.Annotatef(err, "unable to load thread object")
+	}
+	if vocab.IsNil(it) {
+		return nil, errors.Newf("unable to load thread object, nil item: %s", iri)
+	}
+
+	w := c.threadWalker(ctx)
+	if c.threadContext {
+		w.loadConversation(it)
+	}
+
+	t := Thread{Node: w.node(iri, it, nil)}
+	t.Root = w.ancestors(t.Node)
+	w.descendants(t.Node)
+	t.Truncated = w.truncated
+	return &t, nil
+}
+
+type threadWalker struct {
+	c   C
+	ctx context.Context
+
+	maxAncestors int
+	maxDepth     int
+	maxSize      int
+	truncated    bool
+
+	nodes map[vocab.IRI]*ThreadNode
+
+	// NOTE(marius): when the objects have been loaded from the conversation collection,
+	// we don't need to walk the replies collections anymore.
+	conversation bool
+	known        map[vocab.IRI]vocab.Item
+	children     map[vocab.IRI]vocab.IRIs
+}
+
+func (c C) threadWalker(ctx context.Context) *threadWalker {
+	w := threadWalker{
+		c:            c,
+		ctx:          ctx,
+		maxAncestors: c.threadAncestors,
+		maxDepth:     c.threadDepth,
+		maxSize:      c.threadSize,
+		nodes:        make(map[vocab.IRI]*ThreadNode),
+		known:        make(map[vocab.IRI]vocab.Item),
+		children:     make(map[vocab.IRI]vocab.IRIs),
+	}
+	if w.maxAncestors <= 0 {
+		w.maxAncestors = defaultThreadAncestors
+	}
+	if w.maxDepth <= 0 {
+		w.maxDepth = defaultThreadDepth
+	}
+	if w.maxSize <= 0 {
+		w.maxSize = defaultThreadSize
+	}
+	return &w
+}
+
+func (w *threadWalker) node(iri vocab.IRI, it vocab.Item, err error) *ThreadNode {
+	n := &ThreadNode{IRI: iri, Item: it, Err: err}
+	w.nodes[iri] = n
+	return n
+}
+
+func (w *threadWalker) full() bool {
+	return len(w.nodes) >= w.maxSize
+}
+
+func (w *threadWalker) get(iri vocab.IRI) (vocab.Item, error) {
+	if it, ok := w.known[iri]; ok {
+		return it, nil
+	}
+	return w.c.loadCtx(w.ctx, iri)
+}
+
+// ancestors walks the inReplyTo properties up from n, and returns the root of the thread.
+func (w *threadWalker) ancestors(n *ThreadNode) *ThreadNode {
+	for i := 0; ; i++ {
+		parent := inReplyTo(n.Item)
+		if len(parent) == 0 {
+			return n
+		}
+		if _, ok := w.nodes[parent]; ok {
+			w.c.l.WithContext(Ctx{"iri": parent}).Warnf("reply cycle found in thread")
+			return n
+		}
+		if i == w.maxAncestors || w.full() {
+			w.truncated = true
+			return n
+		}
+		it, err := w.get(parent)
+		p := w.node(parent, it, err)
+		p.Replies = append(p.Replies, n)
+		n.Parent = p
+		n = p
+	}
+}
+
+// descendants walks the replies down from n, level by level.
+func (w *threadWalker) descendants(n *ThreadNode) {
+	level := []*ThreadNode{n}
+	for depth := 0; len(level) > 0; depth++ {
+		next := make([]*ThreadNode, 0)
+		for _, p := range level {
+			if p.Placeholder() || !w.hasReplies(p) {
+				continue
+			}
+			if depth == w.maxDepth || w.full() {
+				w.truncated = true
+				return
+			}
+			ids := w.replies(p)
+			if room := w.maxSize - len(w.nodes); len(ids) > room {
+				w.truncated = true
+				ids = ids[:room]
+			}
+			loaded := w.load(ids)
+			for _, id := range ids {
+				res := loaded[id]
+				r := w.node(id, res.Item, res.Err)
+				r.Parent = p
+				p.Replies = append(p.Replies, r)
+				next = append(next, r)
+			}
+		}
+		level = next
+	}
+}
+
+func (w *threadWalker) hasReplies(p *ThreadNode) bool {
+	if w.conversation {
+		return len(w.children[p.IRI]) > 0
+	}
+	return !vocab.IsNil(vocab.Replies.Of(p.Item))
+}
+
+// replies returns the IDs of the replies to the object of the p node, which are not already in the thread.
+func (w *threadWalker) replies(p *ThreadNode) vocab.IRIs {
+	ids := make(vocab.IRIs, 0)
+	add := func(it vocab.Item) {
+		id := it.GetLink()
+		if _, ok := w.nodes[id]; ok || len(id) == 0 || ids.Contains(id) {
+			return
+		}
+		if !vocab.IsIRI(it) {
+			w.known[id] = it
+		}
+		ids = append(ids, id)
+	}
+
+	if w.conversation {
+		for _, id := range w.children[p.IRI] {
+			add(id)
+		}
+		return ids
+	}
+
+	col := vocab.Replies.Of(p.Item)
+	if vocab.IsNil(col) {
+		return ids
+	}
+	for it, err := range w.c.Items(w.ctx, col.GetLink()) {
+		if err != nil {
+			w.c.l.WithContext(Ctx{"iri": col.GetLink(), "err": err.Error()}).Warnf("unable to load replies")
+			break
+		}
+		if vocab.IsNil(it) {
+			continue
+		}
+		add(it)
+		if len(ids) >= w.maxSize {
+			break
+		}
+	}
+	return ids
+}
+
+// load dereferences the ids which are not already known.
+func (w *threadWalker) load(ids vocab.IRIs) map[vocab.IRI]LoadResult {
+	res := make(map[vocab.IRI]LoadResult, len(ids))
+	toLoad := make(vocab.IRIs, 0)
+	for _, id := range ids {
+		if it, ok := w.known[id]; ok {
+			res[id] = LoadResult{Item: it}
+			continue
+		}
+		toLoad = append(toLoad, id)
+	}
+	for id, r := range w.c.LoadIRIs(w.ctx, toLoad...) {
+		res[id] = r
+	}
+	return res
+}
+
+// loadConversation loads the objects from the collection in the context property of the it [vocab.Item].
+func (w *threadWalker) loadConversation(it vocab.Item) {
+	var conv vocab.IRI
+	// NOTE(marius): the "conversation" property that some servers use is not part of the vocabulary,
+	// so we can only look at the "context" one.
+	_ = vocab.OnObject(it, func(o *vocab.Object) error {
+		if !vocab.IsNil(o.Context) {
+			conv = o.Context.GetLink()
+		}
+		return nil
+	})
+	if len(conv) == 0 {
+		return
+	}
+
+	ids := make(vocab.IRIs, 0)
+	for ob, err := range w.c.Items(w.ctx, conv) {
+		if err != nil {
+			// NOTE(marius): the context is not a collection that we can use, so we fall back to the replies
+			w.c.l.WithContext(Ctx{"iri": conv, "err": err.Error()}).Warnf("unable to load conversation")
+			return
+		}
+		if vocab.IsNil(ob) {
+			continue
+		}
+		if len(ids) == w.maxSize {
+			w.truncated = true
+			break
+		}
+		if !vocab.IsIRI(ob) {
+			w.known[ob.GetLink()] = ob
+		}
+		ids = append(ids, ob.GetLink())
+	}
+
+	w.conversation = true
+	loaded := w.load(ids)
+	for _, id := range ids {
+		res := loaded[id]
+		if res.Err != nil || vocab.IsNil(res.Item) {
+			continue
+		}
+		w.known[id] = res.Item
+		if parent := inReplyTo(res.Item); len(parent) > 0 && !w.children[parent].Contains(id) {
+			w.children[parent] = append(w.children[parent], id)
+		}
+	}
+}
+
+// inReplyTo returns the IRI of the first object the it [vocab.Item] replies to.
+func inReplyTo(it vocab.Item) vocab.IRI {
+	var parent vocab.IRI
+	_ = vocab.OnObject(it, func(o *vocab.Object) error {
+		if vocab.IsNil(o.InReplyTo) {
+			return nil
+		}
+		if vocab.IsItemCollection(o.InReplyTo) {
+			_ = vocab.OnItemCollection(o.InReplyTo, func(col *vocab.ItemCollection) error {
+				if len(*col) > 0 && !vocab.IsNil(col.First()) {
+					parent = col.First().GetLink()
+				}
+				return nil
+			})
+			return nil
+		}
+		parent = o.InReplyTo.GetLink()
+		return nil
+	})
+	return parent
+}
@@ -0,0 +1,196 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"git.sr.ht/~mariusor/lw"
+	vocab "github.com/go-ap/activitypub"
+	"github.com/go-ap/errors"
+	"github.com/google/go-cmp/cmp"
+)
+
+func mockNote(id, parent vocab.IRI, withReplies bool) *vocab.Object {
+	n := &vocab.Object{ID: id, Type: vocab.NoteType, Context: vocab.IRI("http://example.com/contexts/1")}
+	if len(parent) > 0 {
+		n.InReplyTo = parent
+	}
+	if withReplies {
+		n.Replies = id + "/replies"
+	}
+	return n
+}
+
+func mockReplies(id vocab.IRI, items ...vocab.Item) *vocab.OrderedCollection {
+	return &vocab.OrderedCollection{ID: id, Type: vocab.OrderedCollectionType, OrderedItems: items}
+}
+
+func mockThreadObjects() map[string]vocab.Item {
+	return map[string]vocab.Item{
+		"/notes/root":         mockNote("http://example.com/notes/root", "", true),
+		"/notes/root/replies": mockReplies("http://example.com/notes/root/replies", vocab.IRI("http://example.com/notes/1"), vocab.IRI("http://example.com/notes/gone")),
+		"/notes/1":            mockNote("http://example.com/notes/1", "http://example.com/notes/root", true),
+		"/notes/1/replies":    mockReplies("http://example.com/notes/1/replies", vocab.IRI("http://example.com/notes/2"), vocab.IRI("http://example.com/notes/missing")),
+		"/notes/2":            mockNote("http://example.com/notes/2", "http://example.com/notes/1", true),
+		"/notes/2/replies":    mockReplies("http://example.com/notes/2/replies", mockNote("http://example.com/notes/3", "http://example.com/notes/2", false)),
+		"/notes/3":            mockNote("http://example.com/notes/3", "http://example.com/notes/2", false),
+		"/notes/4":            mockNote("http://example.com/notes/4", "http://example.com/notes/2", false),
+		"/notes/5":            mockNote("http://example.com/notes/5", "http://example.com/notes/gone", false),
+		"/contexts/1": mockReplies("http://example.com/contexts/1",
+			vocab.IRI("http://example.com/notes/root"),
+			vocab.IRI("http://example.com/notes/1"),
+			vocab.IRI("http://example.com/notes/2"),
+			vocab.IRI("http://example.com/notes/3"),
+			vocab.IRI("http://example.com/notes/4"),
+		),
+	}
+}
+
+func mockThreadHandler(objects map[string]vocab.Item) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/notes/gone" {
+			raw, _ := vocab.MarshalJSON(&vocab.Tombstone{ID: "http://example.com/notes/gone", Type: vocab.TombstoneType})
+			w.WriteHeader(http.StatusGone)
+			_, _ = w.Write(raw)
+			return
+		}
+		mockPagesHandler(objects)(w, r)
+	}
+}
+
+// dumpThread renders the tree under n with one object per line, indented by its depth.
+func dumpThread(n *ThreadNode) string {
+	s := strings.Builder{}
+	var dump func(n *ThreadNode, depth int)
+	dump = func(n *ThreadNode, depth int) {
+		s.WriteString(strings.Repeat("  ", depth))
+		s.WriteString(string(n.IRI))
+		if n.Placeholder() {
+			s.WriteString(" (placeholder)")
+		}
+		s.WriteString("\n")
+		for _, r := range n.Replies {
+			dump(r, depth+1)
+		}
+	}
+	if n != nil {
+		dump(n, 0)
+	}
+	return s.String()
+}
+
+func TestC_Thread(t *testing.T) {
+	tests := []struct {
+		name          string
+		iri           vocab.IRI
+		opts          []OptionFn
+		want          string
+		wantNode      vocab.IRI
+		wantTruncated bool
+		wantErr       error
+	}{
+		{
+			name:    "missing object",
+			iri:     "http://example.com/notes/missing",
+			wantErr: errors.Annotatef(errf("invalid status received").iri("http://example.com/notes/missing").annotate(errors.NotFoundf("/notes/missing not found")), "unable to load thread object"),
+		},
+		{
+			name:     "ancestors and descendants",
+			iri:      "http://example.com/notes/1",
+			wantNode: "http://example.com/notes/1",
+			want: `http://example.com/notes/root
+  http://example.com/notes/1
+    http://example.com/notes/2
+      http://example.com/notes/3
+    http://example.com/notes/missing (placeholder)
+`,
+		},
+		{
+			name:     "only ancestors",
+			iri:      "http://example.com/notes/3",
+			wantNode: "http://example.com/notes/3",
+			want: `http://example.com/notes/root
+  http://example.com/notes/1
+    http://example.com/notes/2
+      http://example.com/notes/3
+`,
+		},
+		{
+			name:     "deleted root",
+			iri:      "http://example.com/notes/5",
+			wantNode: "http://example.com/notes/5",
+			want: `http://example.com/notes/gone (placeholder)
+  http://example.com/notes/5
+`,
+		},
+		{
+			name:          "limits",
+			iri:           "http://example.com/notes/1",
+			opts:          []OptionFn{WithThreadLimits(1, 1, 10)},
+			wantNode:      "http://example.com/notes/1",
+			wantTruncated: true,
+			want: `http://example.com/notes/root
+  http://example.com/notes/1
+    http://example.com/notes/2
+    http://example.com/notes/missing (placeholder)
+`,
+		},
+		{
+			name:          "size limit",
+			iri:           "http://example.com/notes/1",
+			opts:          []OptionFn{WithThreadLimits(5, 5, 3)},
+			wantNode:      "http://example.com/notes/1",
+			wantTruncated: true,
+			want: `http://example.com/notes/root
+  http://example.com/notes/1
+    http://example.com/notes/2
+`,
+		},
+		{
+			name:     "conversation context",
+			iri:      "http://example.com/notes/1",
+			opts:     []OptionFn{WithConversationContext()},
+			wantNode: "http://example.com/notes/1",
+			want: `http://example.com/notes/root
+  http://example.com/notes/1
+    http://example.com/notes/2
+      http://example.com/notes/3
+      http://example.com/notes/4
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(mockThreadHandler(mockThreadObjects()))
+			defer srv.Close()
+
+			c := C{
+				c: srv.Client(),
+				l: lw.Dev(lw.SetOutput(t.Output())),
+			}
+			for _, fn := range tt.opts {
+				fn(&c)
+			}
+
+			got, err := c.Thread(context.Background(), tt.iri)
+			if !cmp.Equal(err, tt.wantErr, EquateWeakErrors("")) {
+				t.Errorf("Thread() error = %s", cmp.Diff(tt.wantErr, err, EquateWeakErrors("")))
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if got.Node.IRI != tt.wantNode {
+				t.Errorf("Thread() node = %s, want %s", got.Node.IRI, tt.wantNode)
+			}
+			if got.Truncated != tt.wantTruncated {
+				t.Errorf("Thread() truncated = %t, want %t", got.Truncated, tt.wantTruncated)
+			}
+			if dump := dumpThread(got.Root); dump != tt.want {
+				t.Errorf("Thread() got = %s", cmp.Diff(tt.want, dump))
+			}
+		})
+	}
+}
@@ -0,0 +1,289 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+
+	vocab "github.com/go-ap/activitypub"
+	"github.com/go-ap/errors"
+)
+
+// ContentTypeJRD is the media type of the WebFinger responses.
+//
+// https://www.rfc-editor.org/rfc/rfc7033#section-10.2
+const ContentTypeJRD = "application/jrd+json"
+
+// ContentTypeXRD is the media type of the XML host-meta documents.
+//
+// https://www.rfc-editor.org/rfc/rfc6415#section-3
+const ContentTypeXRD = "application/xrd+xml"
+
+// JRD is the JSON Resource Descriptor returned by WebFinger lookups.
+//
+// https://www.rfc-editor.org/rfc/rfc7033#section-4.4
+type JRD struct {
+	Subject    string             `json:"subject"`
+	Aliases    []string           `json:"aliases,omitempty"`
+	Properties map[string]*string `json:"properties,omitempty"`
+	Links      []JRDLink          `json:"links,omitempty"`
+}
+
+// JRDLink is a link of a JSON Resource Descriptor.
+type JRDLink struct {
+	Rel        string             `json:"rel"`
+	Type       string             `json:"type,omitempty"`
+	Href       string             `json:"href,omitempty"`
+	Template   string             `json:"template,omitempty"`
+	Titles     map[string]string  `json:"titles,omitempty"`
+	Properties map[string]*string `json:"properties,omitempty"`
+}
+
+// ActorIRI returns the IRI of the "self" link which has an ActivityPub media type.
+func (j JRD) ActorIRI() vocab.IRI {
+	for _, l := range j.Links {
+		if l.Rel == "self" && len(l.Href) > 0 && isActivityPubMediaType(l.Type) {
+			return vocab.IRI(l.Href)
+		}
+	}
+	return ""
+}
+
+func isActivityPubMediaType(typ string) bool {
+	mt, params, err := mime.ParseMediaType(typ)
+	if err != nil {
+		return false
+	}
+	switch mt {
+	case ContentTypeJsonActivity:
+		return true
+	case "application/ld+json":
+		// NOTE(marius): the ld+json media type is an ActivityPub one only with the ActivityStreams profile
+		profile, ok := params["profile"]
+		return !ok || profile == vocab.ActivityBaseURI.String()
+	}
+	return false
+}
+
+// splitHandle returns the user and host parts of a "@user@host", "user@host" or "acct:user@host" handle.
+func splitHandle(handle string) (string, string, error) {
+	h := strings.TrimPrefix(strings.TrimPrefix(handle, "acct:"), "@")
+	at := strings.LastIndex(h, "@")
+	if at <= 0 || at == len(h)-1 {
+		return "", "", errors.Newf("invalid handle %q", handle)
+	}
+	return h[:at], h[at+1:], nil
+}
+
+// WebFinger looks up the resource, which can be an "acct:user@host" URI, or an URL, on the host it belongs to.
+//
+// https://www.rfc-editor.org/rfc/rfc7033
+func (c C) WebFinger(ctx context.Context, resource string) (*JRD, error) {
+	var host string
+	if strings.HasPrefix(resource, "acct:") {
+		_, h, err := splitHandle(resource)
+		if err != nil {
+			return nil, err
+		}
+		host = h
+	} else {
+		u, err := url.Parse(resource)
+		if err != nil || len(u.Host) == 0 {
+			return nil, errors.Newf("invalid WebFinger resource %q", resource)
+		}
+		host = u.Host
+	}
+
+	u := url.URL{Scheme: "https", Host: host, Path: "/.well-known/webfinger", RawQuery: url.Values{"resource": {resource}}.Encode()}
+	jrd, err := c.jrd(ctx, u.String())
+	if err == nil {
+		return jrd, nil
+	}
+
+	// NOTE(marius): some servers expose WebFinger only through the LRDD template of their host-meta document,
+	// so we try that before giving up.
+	meta, merr := c.HostMeta(ctx, host)
+	if merr != nil {
+		return nil, err
+	}
+	tpl := meta.lrdd()
+	if len(tpl) == 0 || strings.HasPrefix(tpl, "https://"+host+"/.well-known/webfinger?") {
+		return nil, err
+	}
+	return c.jrd(ctx, strings.ReplaceAll(tpl, "{uri}", url.QueryEscape(resource)))
+}
+
+// HostMeta loads the host-meta document of the host, in either its XRD or its JSON form.
+//
+// https://www.rfc-editor.org/rfc/rfc6415
+func (c C) HostMeta(ctx context.Context, host string) (*JRD, error) {
+	u := url.URL{Scheme: "https", Host: host, Path: "/.well-known/host-meta"}
+	return c.jrd(ctx, u.String())
+}
+
+func (j JRD) lrdd() string {
+	for _, l := range j.Links {
+		if l.Rel == "lrdd" && strings.Contains(l.Template, "{uri}") {
+			return l.Template
+		}
+	}
+	return ""
+}
+
+// xrd is the XML form of a resource descriptor.
+//
+// http://docs.oasis-open.org/xri/xrd/v1.0/xrd-1.0.html
+type xrd struct {
+	XMLName xml.Name  `xml:"XRD"`
+	Subject string    `xml:"Subject"`
+	Aliases []string  `xml:"Alias"`
+	Links   []xrdLink `xml:"Link"`
+}
+
+type xrdLink struct {
+	Rel      string `xml:"rel,attr"`
+	Type     string `xml:"type,attr"`
+	Href     string `xml:"href,attr"`
+	Template string `xml:"template,attr"`
+}
+
+// parseDescriptor decodes a resource descriptor, from its XRD form if the typ media type, or the content
+// of the body says so, or from its JSON form otherwise.
+func parseDescriptor(typ string, body []byte) (*JRD, error) {
+	mt, _, _ := mime.ParseMediaType(typ)
+	isXML := strings.HasSuffix(mt, "xml")
+	if len(mt) == 0 {
+		isXML = bytes.HasPrefix(bytes.TrimSpace(body), []byte("<"))
+	}
+
+	jrd := JRD{}
+	if !isXML {
+		if err := json.Unmarshal(body, &jrd); err != nil {
+			return nil, err
+		}
+		return &jrd, nil
+	}
+
+	x := xrd{}
+	if err := xml.Unmarshal(body, &x); err != nil {
+		return nil, err
+	}
+	jrd.Subject = x.Subject
+	jrd.Aliases = x.Aliases
+	for _, l := range x.Links {
+		jrd.Links = append(jrd.Links, JRDLink{Rel: l.Rel, Type: l.Type, Href: l.Href, Template: l.Template})
+	}
+	return &jrd, nil
+}
+
+func (c C) jrd(ctx context.Context, u string) (*JRD, error) {
+	body, typ, err := c.fetchDocument(ctx, u, ContentTypeJRD+", "+ContentTypeXRD+", "+ContentTypeJson)
+	if err != nil {
+		return nil, err
+	}
+	jrd, err := parseDescriptor(typ, body)
+	if err != nil {
+		return nil, errf("invalid resource descriptor").iri(vocab.IRI(u)).annotate(err)
+	}
+	return jrd, nil
+}
+
+// fetchDocument loads the non ActivityPub document found at the u URL, and it returns its body and its media type.
+func (c C) fetchDocument(ctx context.Context, u string, accept string) ([]byte, string, error) {
+	req, err := FetchRequest(ctx, u, http.MethodGet)
+	if err != nil {
+		return nil, "", err
+	}
+	req.Header.Set("Accept", accept)
+
+	res, err := c.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer func() {
+		_ = res.Body.Close()
+	}()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	if res.StatusCode != http.StatusOK {
+		e := errf("invalid status received").status(res.StatusCode).iri(vocab.IRI(u))
+		if errb, _ := errors.UnmarshalJSON(body); len(errb) > 0 {
+			return nil, "", e.annotate(errors.Join(errb...))
+		}
+		return nil, "", e.annotate(fmt.Errorf("%s", body[:min(512, len(body))]))
+	}
+	return body, res.Header.Get("Content-Type"), nil
+}
+
+// ActorByHandle resolves the handle, in the "@user@host" form, to an actor, using a WebFinger lookup.
+func (c C) ActorByHandle(ctx context.Context, handle string) (*vocab.Actor, error) {
+	user, host, err := splitHandle(handle)
+	if err != nil {
+		return nil, err
+	}
+	jrd, err := c.WebFinger(ctx, "acct:"+user+"@"+host)
+	if err != nil {
+		return nil, errors.Annotatef(err, "unable to resolve %s", handle)
+	}
+	iri := jrd.ActorIRI()
+	if len(iri) == 0 {
+		return nil, errors.NotFoundf("no ActivityPub actor found for %s", handle)
+	}
+	return c.Actor(ctx, iri)
+}
+
+// HandleForActor returns the handle of the actor, in the "@user@host" form, computed from its canonical
+// "acct:" URI. The handle is verified to resolve back to the same actor before being returned.
+func (c C) HandleForActor(ctx context.Context, actor vocab.Item) (string, error) {
+	if vocab.IsNil(actor) {
+		return "", errors.Newf("invalid nil actor")
+	}
+	if vocab.IsIRI(actor) {
+		a, err := c.Actor(ctx, actor.GetLink())
+		if err != nil {
+			return "", err
+		}
+		actor = a
+	}
+	id := actor.GetLink()
+
+	acct := ""
+	// NOTE(marius): the canonical handle can be on a different host than the actor, so we ask the server first.
+	if jrd, err := c.WebFinger(ctx, id.String()); err == nil && strings.HasPrefix(jrd.Subject, "acct:") {
+		acct = jrd.Subject
+	}
+	if len(acct) == 0 {
+		u, err := id.URL()
+		if err != nil {
+			return "", err
+		}
+		_ = vocab.OnActor(actor, func(a *vocab.Actor) error {
+			if name := a.PreferredUsername.First().String(); len(name) > 0 {
+				acct = "acct:" + name + "@" + u.Host
+			}
+			return nil
+		})
+	}
+	if len(acct) == 0 {
+		return "", errors.NotFoundf("unable to find a handle for %s", id)
+	}
+
+	jrd, err := c.WebFinger(ctx, acct)
+	if err != nil {
+		return "", errors.Annotatef(err, "unable to verify handle %s", acct)
+	}
+	if !jrd.ActorIRI().Equal(id) {
+		return "", errors.Newf("handle %s does not resolve to %s", acct, id)
+	}
+	return "@" + strings.TrimPrefix(acct, "acct:"), nil
+}
@@ -0,0 +1,328 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"git.sr.ht/~mariusor/lw"
+	vocab "github.com/go-ap/activitypub"
+	"github.com/go-ap/errors"
+	"github.com/google/go-cmp/cmp"
+)
+
+func mockWebFingerHandler(docs map[string]JRD, actors map[string]vocab.Item) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/.well-known/webfinger" {
+			mockPagesHandler(actors)(w, r)
+			return
+		}
+		jrd, ok := docs[r.URL.Query().Get("resource")]
+		if !ok {
+			errors.NotFound.ServeHTTP(w, r)
+			return
+		}
+		raw, _ := json.Marshal(jrd)
+		w.Header().Set("Content-Type", ContentTypeJRD)
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(raw)
+	}
+}
+
+func Test_splitHandle(t *testing.T) {
+	tests := []struct {
+		handle   string
+		wantUser string
+		wantHost string
+		wantErr  bool
+	}{
+		{handle: "@jdoe@example.com", wantUser: "jdoe", wantHost: "example.com"},
+		{handle: "jdoe@example.com", wantUser: "jdoe", wantHost: "example.com"},
+		{handle: "acct:jdoe@example.com", wantUser: "jdoe", wantHost: "example.com"},
+		{handle: "@jdoe", wantErr: true},
+		{handle: "jdoe@", wantErr: true},
+		{handle: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.handle, func(t *testing.T) {
+			user, host, err := splitHandle(tt.handle)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("splitHandle() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if user != tt.wantUser || host != tt.wantHost {
+				t.Errorf("splitHandle() = %s, %s, want %s, %s", user, host, tt.wantUser, tt.wantHost)
+			}
+		})
+	}
+}
+
+func TestJRD_ActorIRI(t *testing.T) {
+	tests := []struct {
+		name  string
+		links []JRDLink
+		want  vocab.IRI
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name: "activity+json",
+			links: []JRDLink{
+				{Rel: "http://webfinger.net/rel/profile-page", Type: "text/html", Href: "https://example.com/@jdoe"},
+				{Rel: "self", Type: "application/activity+json", Href: "https://example.com/users/jdoe"},
+			},
+			want: "https://example.com/users/jdoe",
+		},
+		{
+			name: "ld+json with profile",
+			links: []JRDLink{
+				{Rel: "self", Type: `application/ld+json; profile="https://www.w3.org/ns/activitystreams"`, Href: "https://example.com/users/jdoe"},
+			},
+			want: "https://example.com/users/jdoe",
+		},
+		{
+			name: "self link without ActivityPub media type",
+			links: []JRDLink{
+				{Rel: "self", Type: "text/html", Href: "https://example.com/@jdoe"},
+				{Rel: "self", Type: `application/ld+json; profile="https://example.com/other"`, Href: "https://example.com/other/jdoe"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := (JRD{Links: tt.links}).ActorIRI(); got != tt.want {
+				t.Errorf("ActorIRI() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestC_ActorByHandle(t *testing.T) {
+	docs := map[string]JRD{
+		"acct:jdoe@example.com": {
+			Subject: "acct:jdoe@example.com",
+			Links: []JRDLink{
+				{Rel: "self", Type: ContentTypeJsonActivity, Href: "https://example.com/actors/jdoe"},
+			},
+		},
+		"acct:html@example.com": {
+			Subject: "acct:html@example.com",
+			Links: []JRDLink{
+				{Rel: "self", Type: "text/html", Href: "https://example.com/@html"},
+			},
+		},
+	}
+	actors := map[string]vocab.Item{
+		"/actors/jdoe": &vocab.Actor{ID: "https://example.com/actors/jdoe", Type: vocab.PersonType},
+	}
+	tests := []struct {
+		name    string
+		handle  string
+		want    *vocab.Actor
+		wantErr error
+	}{
+		{
+			name:    "invalid handle",
+			handle:  "@jdoe",
+			wantErr: errors.Newf(`invalid handle "@jdoe"`),
+		},
+		{
+			name:   "found",
+			handle: "@jdoe@example.com",
+			want:   &vocab.Actor{ID: "https://example.com/actors/jdoe", Type: vocab.PersonType},
+		},
+		{
+			name:    "no ActivityPub link",
+			handle:  "@html@example.com",
+			wantErr: errors.NotFoundf("no ActivityPub actor found for @html@example.com"),
+		},
+		{
+			name:    "not found",
+			handle:  "@alice@example.com",
+			wantErr: errors.Annotatef(errf("invalid status received").iri("https://example.com/.well-known/webfinger?resource=acct%3Aalice%40example.com").annotate(errors.NotFoundf("/.well-known/webfinger not found")), "unable to resolve @alice@example.com"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewTLSServer(mockWebFingerHandler(docs, actors))
+			defer srv.Close()
+
+			c := C{
+				c: srv.Client(),
+				l: lw.Dev(lw.SetOutput(t.Output())),
+			}
+			got, err := c.ActorByHandle(context.Background(), tt.handle)
+			if !cmp.Equal(err, tt.wantErr, EquateWeakErrors("")) {
+				t.Errorf("ActorByHandle() error = %s", cmp.Diff(tt.wantErr, err, EquateWeakErrors("")))
+			}
+			if !cmp.Equal(got, tt.want, EquateItems) {
+				t.Errorf("ActorByHandle() got = %s", cmp.Diff(tt.want, got, EquateItems))
+			}
+		})
+	}
+}
+
+func Test_parseDescriptor(t *testing.T) {
+	tests := []struct {
+		name    string
+		typ     string
+		body    string
+		want    *JRD
+		wantErr bool
+	}{
+		{
+			name: "XRD",
+			typ:  ContentTypeXRD,
+			body: `<?xml version="1.0" encoding="UTF-8"?>
+<XRD xmlns="http://docs.oasis-open.org/ns/xri/xrd-1.0">
+  <Link rel="lrdd" template="https://example.com/.well-known/webfinger?resource={uri}"/>
+</XRD>`,
+			want: &JRD{Links: []JRDLink{{Rel: "lrdd", Template: "https://example.com/.well-known/webfinger?resource={uri}"}}},
+		},
+		{
+			name: "XRD without content type",
+			body: `<XRD><Subject>acct:jdoe@example.com</Subject><Alias>https://example.com/@jdoe</Alias></XRD>`,
+			want: &JRD{Subject: "acct:jdoe@example.com", Aliases: []string{"https://example.com/@jdoe"}},
+		},
+		{
+			name: "JSON",
+			typ:  "application/json; charset=utf-8",
+			body: `{"links":[{"rel":"lrdd","template":"https://example.com/.well-known/webfinger?resource={uri}"}]}`,
+			want: &JRD{Links: []JRDLink{{Rel: "lrdd", Template: "https://example.com/.well-known/webfinger?resource={uri}"}}},
+		},
+		{
+			name:    "invalid",
+			typ:     ContentTypeJRD,
+			body:    `<XRD/>`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseDescriptor(tt.typ, []byte(tt.body))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseDescriptor() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if !cmp.Equal(got, tt.want) {
+				t.Errorf("parseDescriptor() got = %s", cmp.Diff(tt.want, got))
+			}
+		})
+	}
+}
+
+func TestC_WebFinger_hostMetaFallback(t *testing.T) {
+	docs := map[string]JRD{
+		"acct:jdoe@example.com": {
+			Subject: "acct:jdoe@example.com",
+			Links: []JRDLink{
+				{Rel: "self", Type: ContentTypeJsonActivity, Href: "https://example.com/actors/jdoe"},
+			},
+		},
+	}
+	wf := mockWebFingerHandler(docs, nil)
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/webfinger":
+			errors.NotFound.ServeHTTP(w, r)
+		case "/.well-known/host-meta":
+			w.Header().Set("Content-Type", ContentTypeXRD)
+			_, _ = w.Write([]byte(`<XRD xmlns="http://docs.oasis-open.org/ns/xri/xrd-1.0"><Link rel="lrdd" template="https://example.com/lrdd?resource={uri}"/></XRD>`))
+		case "/lrdd":
+			r.URL.Path = "/.well-known/webfinger"
+			wf(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	c := C{
+		c: srv.Client(),
+		l: lw.Dev(lw.SetOutput(t.Output())),
+	}
+	got, err := c.WebFinger(context.Background(), "acct:jdoe@example.com")
+	if err != nil {
+		t.Fatalf("WebFinger() error = %s", err)
+	}
+	if want := docs["acct:jdoe@example.com"]; !cmp.Equal(*got, want) {
+		t.Errorf("WebFinger() got = %s", cmp.Diff(want, *got))
+	}
+}
+
+func TestC_HandleForActor(t *testing.T) {
+	docs := map[string]JRD{
+		"https://example.com/actors/1": {Subject: "acct:jdoe@example.com"},
+		"acct:jdoe@example.com": {
+			Subject: "acct:jdoe@example.com",
+			Links:   []JRDLink{{Rel: "self", Type: ContentTypeJsonActivity, Href: "https://example.com/actors/1"}},
+		},
+		"acct:alice@example.com": {
+			Subject: "acct:alice@example.com",
+			Links:   []JRDLink{{Rel: "self", Type: ContentTypeJsonActivity, Href: "https://example.com/actors/alice"}},
+		},
+		"acct:bob@example.com": {
+			Subject: "acct:bob@example.com",
+			Links:   []JRDLink{{Rel: "self", Type: ContentTypeJsonActivity, Href: "https://example.com/actors/impostor"}},
+		},
+	}
+	actors := map[string]vocab.Item{
+		"/actors/alice": &vocab.Actor{
+			ID:                "https://example.com/actors/alice",
+			Type:              vocab.PersonType,
+			PreferredUsername: vocab.DefaultNaturalLanguage("alice"),
+		},
+	}
+	tests := []struct {
+		name    string
+		actor   vocab.Item
+		want    string
+		wantErr error
+	}{
+		{
+			name:    "nil",
+			wantErr: errors.Newf("invalid nil actor"),
+		},
+		{
+			name:  "handle from the actor's server",
+			actor: &vocab.Actor{ID: "https://example.com/actors/1", Type: vocab.PersonType},
+			want:  "@jdoe@example.com",
+		},
+		{
+			name:  "handle from preferred username",
+			actor: vocab.IRI("https://example.com/actors/alice"),
+			want:  "@alice@example.com",
+		},
+		{
+			name: "handle doesn't resolve to the actor",
+			actor: &vocab.Actor{
+				ID:                "https://example.com/actors/bob",
+				Type:              vocab.PersonType,
+				PreferredUsername: vocab.DefaultNaturalLanguage("bob"),
+			},
+			wantErr: errors.Newf("handle acct:bob@example.com does not resolve to https://example.com/actors/bob"),
+		},
+		{
+			name:    "no handle",
+			actor:   &vocab.Actor{ID: "https://example.com/actors/nobody", Type: vocab.PersonType},
+			wantErr: errors.NotFoundf("unable to find a handle for https://example.com/actors/nobody"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewTLSServer(mockWebFingerHandler(docs, actors))
+			defer srv.Close()
+
+			c := C{
+				c: srv.Client(),
+				l: lw.Dev(lw.SetOutput(t.Output())),
+			}
+			got, err := c.HandleForActor(context.Background(), tt.actor)
+			if !cmp.Equal(err, tt.wantErr, EquateWeakErrors("")) {
+				t.Errorf("HandleForActor() error = %s", cmp.Diff(tt.wantErr, err, EquateWeakErrors("")))
+			}
+			if got != tt.want {
+				t.Errorf("HandleForActor() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}